@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/buck119br/psss/psss"
+)
+
+// openCapture points the clients of the package level flags at the transport
+// of -capture or -replay.
+func openCapture() (err error) {
+	if *flagAllNetNS {
+		return fmt.Errorf("-all-netns can not be captured or replayed")
+	}
+	if len(*flagCapture) > 0 {
+		psss.DiagTransport, err = psss.CreateCapture(*flagCapture, psss.NetNamespace)
+		return err
+	}
+	if psss.NetNamespace != nil {
+		return fmt.Errorf("a replay has no namespaces")
+	}
+	if *flagWatch && *flagPoll == 0 {
+		return fmt.Errorf("-watch needs -poll to replay")
+	}
+	if *flagKill {
+		// the sockets of a replay are not there to be closed
+		return fmt.Errorf("-K can not be replayed")
+	}
+	if psss.DiagTransport, err = psss.OpenReplay(*flagReplay); err != nil {
+		return err
+	}
+	psss.ProcRoot = filepath.Join(*flagReplay, psss.CaptureProcDir)
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/buck119br/psss/psss"
+	"golang.org/x/sys/unix"
+)
+
+// Diagnose prints the TCP connections psss.DiagnoseTCP finds problems with,
+// each followed by its reasons.
+func Diagnose() {
+	c := psss.NewClient()
+	c.States = psss.SsFilter
+	c.Filter = psss.ExprFilter
+	c.Info = true
+	c.Process = true
+	c.NetNS = psss.NetNamespace
+	c.Transport = psss.DiagTransport
+	defer c.Close()
+
+	for _, af := range []int{unix.AF_INET, unix.AF_INET6} {
+		if psss.AfFilter&(1<<af) == 0 {
+			continue
+		}
+		sis, err := c.InetRead(psss.ProtocalTCP, af)
+		if errors.Is(err, psss.ErrTruncatedMessage) {
+			fmt.Fprintf(os.Stderr, "read sockets warning:[%v]\n", err)
+		} else if err != nil {
+			fmt.Printf("read sockets error:[%v]\n", err)
+			continue
+		}
+		for _, report := range psss.DiagnoseTCP(sis, nil) {
+			formatter.Row(&socketRow{af: af, si: report.Info, findings: report.Findings})
+		}
+	}
+	formatter.Flush()
+}
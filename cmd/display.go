@@ -1,122 +1,291 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/buck119br/psss/psss"
 	"golang.org/x/sys/unix"
 )
 
 func ShowSummary() {
-	var format string
 	summary, err := psss.GenericReadSockstat()
 	if err != nil {
-		fmt.Println(err)
+		fmt.Printf("read sockstat error:[%v]\n", err)
 		return
 	}
-	fmt.Println("Transport\t Total\t IPv4\t IPv6\t")
-	for _, pf := range psss.SummaryPF {
-		if len(pf) >= 8 {
-			format = "%s\t %d\t %d\t %d\t\n"
-		} else {
-			format = "%s\t\t %d\t %d\t %d\t\n"
-		}
-		fmt.Printf(format, pf, summary[pf][psss.IPv4String]+summary[pf][psss.IPv6String], summary[pf][psss.IPv4String], summary[pf][psss.IPv6String])
+	v4, v6 := &summary.IPv4, &summary.IPv6
+	fmt.Printf("Total: %d\n", summary.Used)
+	fmt.Printf("TCP:   %d (estab %d, closed %d, orphaned %d, synrecv %d, timewait %d), mem %d, alloc %d\n",
+		summary.TCPTotal,
+		summary.TCPStates[psss.SsESTAB],
+		summary.TCPStates[psss.SsUNCONN],
+		v4.TCP.Orphan,
+		summary.TCPStates[psss.SsSYNRECV],
+		summary.TCPStates[psss.SsTIMEWAIT],
+		v4.TCP.Mem,
+		v4.TCP.Alloc)
+	fmt.Printf("\n")
+	fmt.Printf("%-10s%-10s%-10s%s\n", "Transport", "Total", "IP", "IPv6")
+	rows := []struct {
+		name   string
+		v4, v6 int
+	}{
+		{"RAW", v4.RAW.InUse, v6.RAW.InUse},
+		{"UDP", v4.UDP.InUse, v6.UDP.InUse},
+		{"UDPLITE", v4.UDPLite.InUse, v6.UDPLite.InUse},
+		{"TCP", v4.TCP.InUse, v6.TCP.InUse},
+		{"INET", v4.RAW.InUse + v4.UDP.InUse + v4.UDPLite.InUse + v4.TCP.InUse, v6.RAW.InUse + v6.UDP.InUse + v6.UDPLite.InUse + v6.TCP.InUse},
+		{"FRAG", v4.FRAG.InUse, v6.FRAG.InUse},
+	}
+	for _, row := range rows {
+		fmt.Printf("%-10s%-10d%-10d%d\n", row.name, row.v4+row.v6, row.v4, row.v6)
 	}
+	fmt.Printf("\n")
+	fmt.Printf("UDP mem %d, FRAG memory %d (IP) %d (IPv6)\n", v4.UDP.Mem, v4.FRAG.Memory, v6.FRAG.Memory)
 }
 
-func SocketShow() {
-	if psss.ProtocalFilter&psss.ProtocalUnix != 0 {
-		psss.AddrLengthInit()
-		sis, _ = psss.GenericUnixRead()
-		GenericShow(psss.ProtocalUnix, unix.AF_UNIX)
-	}
-	if psss.ProtocalFilter&psss.ProtocalRAW != 0 && psss.AfFilter&(1<<unix.AF_INET) != 0 {
-		psss.AddrLengthInit()
-		sis, _ = psss.GenericInetRead(psss.ProtocalRAW, unix.AF_INET)
-		GenericShow(psss.ProtocalRAW, unix.AF_INET)
-	}
-	if psss.ProtocalFilter&psss.ProtocalRAW != 0 && psss.AfFilter&(1<<unix.AF_INET6) != 0 {
-		psss.AddrLengthInit()
-		sis, _ = psss.GenericInetRead(psss.ProtocalRAW, unix.AF_INET6)
-		GenericShow(psss.ProtocalRAW, unix.AF_INET6)
+type socketRow struct {
+	af      int
+	si      psss.SocketInfo
+	killed  bool
+	killErr error
+	// only set by --watch
+	event    string
+	oldState string
+	time     time.Time
+	// only set by --diagnose
+	findings []psss.TCPFinding
+}
+
+type column struct {
+	name   string
+	header string
+	value  func(af int, si *psss.SocketInfo) string
+}
+
+var (
+	columns = []column{
+		{"netid", "Netid", netidString},
+		{"state", "State", func(af int, si *psss.SocketInfo) string { return si.StateString() }},
+		{"recv-q", "Recv-Q", func(af int, si *psss.SocketInfo) string { return strconv.FormatUint(uint64(si.RxQueue), 10) }},
+		{"send-q", "Send-Q", func(af int, si *psss.SocketInfo) string { return strconv.FormatUint(uint64(si.TxQueue), 10) }},
+		{"local", "LocalAddress:Port", func(af int, si *psss.SocketInfo) string {
+			return resolver.AddrString(si.LocalAddr, si.Protocal, *flagResolve, !*flagNotResolve)
+		}},
+		{"remote", "RemoteAddress:Port", func(af int, si *psss.SocketInfo) string {
+			return resolver.AddrString(si.RemoteAddr, si.Protocal, *flagResolve, !*flagNotResolve)
+		}},
+		{"users", "Users", func(af int, si *psss.SocketInfo) (users string) {
+			if len(si.Owners) > 0 {
+				users = si.OwnersString()
+			}
+			if len(si.PeerOwners) > 0 {
+				if len(users) > 0 {
+					users += " "
+				}
+				users += si.PeerOwnersString()
+			}
+			return users
+		}},
+		{"netns", "Netns", func(af int, si *psss.SocketInfo) string { return si.NetNS }},
+		{"inode", "Inode", func(af int, si *psss.SocketInfo) string { return strconv.FormatUint(uint64(si.Inode), 10) }},
+		{"uid", "UID", func(af int, si *psss.SocketInfo) string { return strconv.FormatUint(si.UID, 10) }},
+		{"cookie", "Cookie", func(af int, si *psss.SocketInfo) string { return strconv.FormatUint(si.SK, 16) }},
+		{"source", "Source", func(af int, si *psss.SocketInfo) string { return psss.SourceName[si.Source] }},
+		{"rtt", "RTT", func(af int, si *psss.SocketInfo) string {
+			if si.TCPInfo == nil {
+				return ""
+			}
+			return fmt.Sprintf("%.3f/%.3f", float64(si.TCPInfo.Rtt)/1000, float64(si.TCPInfo.Rttvar)/1000)
+		}},
+		{"cwnd", "Cwnd", func(af int, si *psss.SocketInfo) string {
+			if si.TCPInfo == nil {
+				return ""
+			}
+			return strconv.FormatUint(uint64(si.TCPInfo.Snd_cwnd), 10)
+		}},
+		{"bytes_acked", "Bytes-Acked", func(af int, si *psss.SocketInfo) string {
+			if si.TCPInfo == nil {
+				return ""
+			}
+			return strconv.FormatUint(si.TCPInfo.Bytes_acked, 10)
+		}},
+		{"bytes_received", "Bytes-Received", func(af int, si *psss.SocketInfo) string {
+			if si.TCPInfo == nil {
+				return ""
+			}
+			return strconv.FormatUint(si.TCPInfo.Bytes_received, 10)
+		}},
 	}
-	if psss.ProtocalFilter&psss.ProtocalUDP != 0 && psss.AfFilter&(1<<unix.AF_INET) != 0 {
-		psss.AddrLengthInit()
-		sis, _ = psss.GenericInetRead(psss.ProtocalUDP, unix.AF_INET)
-		GenericShow(psss.ProtocalUDP, unix.AF_INET)
+)
+
+func netidString(af int, si *psss.SocketInfo) (netid string) {
+	switch si.Protocal {
+	case psss.ProtocalTCP:
+		netid = "tcp"
+	case psss.ProtocalUDP:
+		netid = "udp"
+	case psss.ProtocalUDPLite:
+		netid = "ulite"
+	case psss.ProtocalRAW:
+		netid = "raw"
+	case psss.ProtocalSCTP:
+		netid = "sctp"
+	case psss.ProtocalDCCP:
+		netid = "dccp"
+	case psss.ProtocalMPTCP:
+		netid = "mptcp"
+	case psss.ProtocalPacket:
+		if si.Type == unix.SOCK_RAW {
+			return "p_raw"
+		}
+		return "p_dgr"
+	case psss.ProtocalNetlink:
+		return "nl"
+	case psss.ProtocalUnix:
+		if name, ok := psss.SocketType[si.Type]; ok {
+			return name
+		}
+		return "dgr"
 	}
-	if psss.ProtocalFilter&psss.ProtocalUDP != 0 && psss.AfFilter&(1<<unix.AF_INET6) != 0 {
-		psss.AddrLengthInit()
-		sis, _ = psss.GenericInetRead(psss.ProtocalUDP, unix.AF_INET6)
-		GenericShow(psss.ProtocalUDP, unix.AF_INET6)
+	switch af {
+	case unix.AF_INET:
+		netid += "4"
+	case unix.AF_INET6:
+		netid += "6"
 	}
-	if psss.ProtocalFilter&psss.ProtocalTCP != 0 && psss.AfFilter&(1<<unix.AF_INET) != 0 {
-		psss.AddrLengthInit()
-		sis, _ = psss.GenericInetRead(psss.ProtocalTCP, unix.AF_INET)
-		GenericShow(psss.ProtocalTCP, unix.AF_INET)
+	return netid
+}
+
+// selectColumns returns the columns given by --columns, or the default set
+// which depends on -p and --all-netns.
+func selectColumns() (selected []column, err error) {
+	names := []string{"netid", "state", "recv-q", "send-q", "local", "remote"}
+	if len(*flagColumns) > 0 {
+		names = strings.Split(*flagColumns, ",")
+	} else {
+		if *flagAllNetNS {
+			names = append([]string{"netns"}, names...)
+		}
+		if *flagProcess {
+			names = append(names, "users")
+		}
 	}
-	if psss.ProtocalFilter&psss.ProtocalTCP != 0 && psss.AfFilter&(1<<unix.AF_INET6) != 0 {
-		psss.AddrLengthInit()
-		sis, _ = psss.GenericInetRead(psss.ProtocalTCP, unix.AF_INET6)
-		GenericShow(psss.ProtocalTCP, unix.AF_INET6)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		found := false
+		for i := range columns {
+			if columns[i].name == name {
+				selected = append(selected, columns[i])
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("invalid column:[%s]", name)
+		}
 	}
+	return selected, nil
 }
 
-func GenericShow(protocal, af int) {
-	if len(sis) == 0 {
-		return
+type socketQuery struct {
+	protocal int
+	af       int
+	explicit *bool // errors are only shown when set, nil for always
+}
+
+// socketQueries is the order in which the protocols are read.
+var socketQueries = []socketQuery{
+	{psss.ProtocalUnix, unix.AF_UNIX, nil},
+	{psss.ProtocalPacket, unix.AF_PACKET, nil},
+	{psss.ProtocalNetlink, unix.AF_NETLINK, nil},
+	{psss.ProtocalRAW, unix.AF_INET, nil},
+	{psss.ProtocalRAW, unix.AF_INET6, nil},
+	{psss.ProtocalUDP, unix.AF_INET, nil},
+	{psss.ProtocalUDP, unix.AF_INET6, nil},
+	{psss.ProtocalUDPLite, unix.AF_INET, flagUDPLite},
+	{psss.ProtocalUDPLite, unix.AF_INET6, flagUDPLite},
+	{psss.ProtocalSCTP, unix.AF_INET, flagSCTP},
+	{psss.ProtocalSCTP, unix.AF_INET6, flagSCTP},
+	{psss.ProtocalDCCP, unix.AF_INET, flagDCCP},
+	{psss.ProtocalDCCP, unix.AF_INET6, flagDCCP},
+	{psss.ProtocalMPTCP, unix.AF_INET, flagMPTCP},
+	{psss.ProtocalMPTCP, unix.AF_INET6, flagMPTCP},
+	{psss.ProtocalTCP, unix.AF_INET, nil},
+	{psss.ProtocalTCP, unix.AF_INET6, nil},
+}
+
+func (q *socketQuery) enabled() bool {
+	if psss.ProtocalFilter&uint64(q.protocal) == 0 {
+		return false
 	}
-	fmt.Printf("Netid\tState\t\tRecv-Q\tSend-Q\t")
-	fmt.Printf("%-*s\t%-*s\t", psss.MaxLocalAddrLength, "LocalAddress:Port", psss.MaxRemoteAddrLength, "RemoteAddress:Port")
-	if *flagProcess {
-		fmt.Printf("Users")
+	return q.protocal == psss.ProtocalUnix || psss.AfFilter&(1<<q.af) != 0
+}
+
+func SocketShow() {
+	if *flagKill {
+		killClient = psss.NewClient()
+		killClient.NetNS = psss.NetNamespace
+		defer killClient.Close()
 	}
-	fmt.Printf("\n")
-	var ok bool
-	for _, si := range sis {
-		switch protocal {
-		case psss.ProtocalTCP:
-			fmt.Printf("tcp")
-		case psss.ProtocalUDP:
-			fmt.Printf("udp")
-		case psss.ProtocalRAW:
-			fmt.Printf("raw")
-		case psss.ProtocalUnix:
-			if _, ok = psss.SocketType[si.Type]; !ok {
-				fmt.Printf("dgr\t")
-			} else {
-				fmt.Printf("%s\t", psss.SocketType[si.Type])
-			}
+	var rows []socketRow
+	for _, q := range socketQueries {
+		if !q.enabled() {
+			continue
 		}
-		switch af {
-		case unix.AF_INET:
-			fmt.Printf("4\t")
-		case unix.AF_INET6:
-			fmt.Printf("6\t")
+		var (
+			sis []psss.SocketInfo
+			err error
+		)
+		switch q.protocal {
+		case psss.ProtocalUnix:
+			sis, err = psss.GenericUnixRead()
+		case psss.ProtocalPacket:
+			sis, err = psss.GenericPacketRead()
+		case psss.ProtocalNetlink:
+			sis, err = psss.GenericNetlinkRead()
+		default:
+			sis, err = psss.GenericInetRead(q.protocal, q.af)
 		}
-		si.GenericInfoPrint()
-		if *flagProcess && len(si.UserName) > 0 {
-			si.ProcInfoPrint()
+		switch {
+		case errors.Is(err, psss.ErrTruncatedMessage):
+			// the rows of the other messages are still good
+			fmt.Fprintf(os.Stderr, "read sockets warning:[%v]\n", err)
+		case err != nil && (q.explicit == nil || *q.explicit):
+			fmt.Printf("read sockets error:[%v]\n", err)
 		}
-		if newlineFlag {
-			fmt.Printf("\n")
+		for i := range sis {
+			rows = append(rows, socketRow{af: q.af, si: sis[i]})
 		}
-		if protocal != psss.ProtocalUnix {
-			if *flagOption && si.Timer != 0 {
-				si.TimerInfoPrint()
-			}
-			if *flagExtended {
-				si.ExtendInfoPrint()
+	}
+	if len(sortKeys) > 0 {
+		sort.SliceStable(rows, func(i, j int) bool {
+			return psss.CompareSocketInfo(&rows[i].si, &rows[j].si, sortKeys) < 0
+		})
+	}
+	if *flagResolve && resolver != nil {
+		addrs := make([]netip.Addr, 0, 2*len(rows))
+		for i := range rows {
+			if rows[i].si.LocalAddr.IsInet() {
+				addrs = append(addrs, rows[i].si.LocalAddr.Addr(), rows[i].si.RemoteAddr.Addr())
 			}
 		}
-		if *flagMemory && len(si.Meminfo) == 8 {
-			si.MeminfoPrint()
-		}
-		if *flagInfo && protocal == psss.ProtocalTCP && si.TCPInfo != nil {
-			si.TCPInfoPrint()
+		resolver.Prefetch(addrs)
+	}
+	for i := range rows {
+		row := &rows[i]
+		if *flagKill && (row.si.Protocal == psss.ProtocalTCP || row.si.Protocal == psss.ProtocalUDP) {
+			row.killed = true
+			row.killErr = killClient.Destroy(&row.si)
 		}
-		fmt.Printf("\n")
+		formatter.Row(row)
 	}
-	fmt.Printf("\n")
+	formatter.Flush()
 }
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/buck119br/psss/psss"
+)
+
+// Formatter turns the rows of SocketShow into output. Flush is called after
+// every read of the sockets and Close once before exiting.
+type Formatter interface {
+	Row(row *socketRow)
+	Flush()
+	Close()
+}
+
+func newFormatter() Formatter {
+	switch {
+	case *flagNDJSON, *flagJSON && *flagWatch:
+		// a watch has no end to close a document at
+		return &jsonFormatter{enc: json.NewEncoder(os.Stdout), stream: true}
+	case *flagJSON:
+		return &jsonFormatter{enc: json.NewEncoder(os.Stdout)}
+	}
+	return &textFormatter{w: os.Stdout, stream: *flagWatch}
+}
+
+// textFormatter prints a table, it needs every row before the widths of the
+// columns are known. With stream set rows are printed as they come, for
+// --watch, and columns grow as wider cells show up.
+type textFormatter struct {
+	w      io.Writer
+	stream bool
+	widths []int
+	rows   []*socketRow
+}
+
+func (f *textFormatter) Row(row *socketRow) {
+	if !f.stream {
+		f.rows = append(f.rows, row)
+		return
+	}
+	cells := rowCells(row)
+	if f.widths == nil {
+		f.widths = headerWidths()
+		f.updateWidths(cells)
+		fmt.Fprintf(f.w, "%-*s ", len(psss.SocketEventName[psss.SocketEventDestroyed]), "Event")
+		f.printHeader()
+	} else {
+		f.updateWidths(cells)
+	}
+	event := row.event
+	if len(row.oldState) > 0 {
+		event += ":" + row.oldState
+	}
+	fmt.Fprintf(f.w, "%-*s ", len(psss.SocketEventName[psss.SocketEventDestroyed]), event)
+	f.printRow(row, cells)
+}
+
+func (f *textFormatter) Flush() {
+	if f.stream || len(f.rows) == 0 {
+		return
+	}
+	cells := make([][]string, len(f.rows))
+	f.widths = headerWidths()
+	for i, row := range f.rows {
+		cells[i] = rowCells(row)
+		f.updateWidths(cells[i])
+	}
+	f.printHeader()
+	for i, row := range f.rows {
+		f.printRow(row, cells[i])
+	}
+	fmt.Fprintf(f.w, "\n")
+	f.rows = f.rows[:0]
+}
+
+func headerWidths() []int {
+	widths := make([]int, len(showColumns))
+	for j := range showColumns {
+		widths[j] = len(showColumns[j].header)
+	}
+	return widths
+}
+
+func rowCells(row *socketRow) []string {
+	cells := make([]string, len(showColumns))
+	for j := range showColumns {
+		cells[j] = showColumns[j].value(row.af, &row.si)
+	}
+	return cells
+}
+
+func (f *textFormatter) updateWidths(cells []string) {
+	for j := range cells {
+		if f.widths[j] < len(cells[j]) {
+			f.widths[j] = len(cells[j])
+		}
+	}
+}
+
+func (f *textFormatter) printHeader() {
+	for j := range showColumns {
+		printCell(f.w, showColumns[j].header, f.widths, j)
+	}
+	fmt.Fprintf(f.w, "\n")
+}
+
+func (f *textFormatter) printRow(row *socketRow, cells []string) {
+	si := &row.si
+	protocal := si.Protocal
+	for j := range cells {
+		printCell(f.w, cells[j], f.widths, j)
+	}
+	if row.killed {
+		if row.killErr != nil {
+			fmt.Fprintf(f.w, " [kill:(%v)]", row.killErr)
+		} else {
+			fmt.Fprintf(f.w, " [kill:(ok)]")
+		}
+	}
+	if newlineFlag {
+		if *flagOneline {
+			fmt.Fprintf(f.w, " ")
+		} else {
+			fmt.Fprintf(f.w, "\n")
+		}
+	}
+	if protocal != psss.ProtocalUnix {
+		if *flagOption && si.Timer != 0 {
+			si.TimerInfoPrint(f.w)
+		}
+		if *flagExtended {
+			si.ExtendInfoPrint(f.w)
+		}
+	}
+	if protocal == psss.ProtocalUnix && *flagExtended {
+		si.UnixInfoPrint(f.w)
+	}
+	if protocal == psss.ProtocalPacket && *flagExtended {
+		si.PacketInfoPrint(f.w)
+	}
+	if protocal == psss.ProtocalNetlink && *flagExtended {
+		si.NetlinkInfoPrint(f.w)
+	}
+	if protocal == psss.ProtocalSCTP && *flagExtended {
+		si.SCTPAddrsPrint(f.w)
+	}
+	if *flagMemory && (len(si.Meminfo) >= psss.SK_MEMINFO_DROPS || si.InetMeminfo != nil) {
+		si.MeminfoPrint(f.w)
+	}
+	if *flagInfo && protocal == psss.ProtocalTCP && si.TCPInfo != nil {
+		si.TCPInfoPrint(f.w)
+	}
+	if *flagInfo && protocal == psss.ProtocalSCTP && si.SCTPInfo != nil {
+		si.SCTPInfoPrint(f.w)
+	}
+	if *flagInfo && protocal == psss.ProtocalMPTCP && si.MPTCPInfo != nil {
+		si.MPTCPInfoPrint(f.w)
+	}
+	fmt.Fprintf(f.w, "\n")
+	for _, finding := range row.findings {
+		fmt.Fprintf(f.w, "\t%s\n", finding)
+	}
+}
+
+func (f *textFormatter) Close() {}
+
+// printCell pads every column but the last one to its width.
+func printCell(w io.Writer, cell string, widths []int, j int) {
+	if j > 0 {
+		fmt.Fprintf(w, " ")
+	}
+	if j == len(widths)-1 {
+		fmt.Fprintf(w, "%s", cell)
+		return
+	}
+	fmt.Fprintf(w, "%-*s", widths[j], cell)
+}
+
+type jsonSocket struct {
+	Event    string `json:"event,omitempty"`
+	OldState string `json:"old_state,omitempty"`
+	Time     string `json:"time,omitempty"`
+	Netid    string `json:"netid"`
+	*psss.SocketRecord
+	Kill     string        `json:"kill,omitempty"`
+	Problems []jsonProblem `json:"problems,omitempty"`
+}
+
+type jsonProblem struct {
+	Problem string `json:"problem"`
+	Reason  string `json:"reason"`
+}
+
+// jsonFormatter writes one array of sockets on Close, or one object per line
+// as soon as a row arrives when stream is set.
+type jsonFormatter struct {
+	enc     *json.Encoder
+	stream  bool
+	sockets []jsonSocket
+}
+
+func (f *jsonFormatter) Row(row *socketRow) {
+	socket := jsonSocket{
+		Netid:        netidString(row.af, &row.si),
+		SocketRecord: row.si.Record(),
+		Event:        row.event,
+		OldState:     row.oldState,
+	}
+	if !row.time.IsZero() {
+		socket.Time = row.time.Format(time.RFC3339Nano)
+	}
+	if row.killed {
+		socket.Kill = "ok"
+		if row.killErr != nil {
+			socket.Kill = row.killErr.Error()
+		}
+	}
+	for _, finding := range row.findings {
+		socket.Problems = append(socket.Problems, jsonProblem{
+			Problem: psss.TCPProblemName[finding.Problem],
+			Reason:  finding.Reason,
+		})
+	}
+	if f.stream {
+		if err := f.enc.Encode(&socket); err != nil {
+			fmt.Fprintf(os.Stderr, "write json error:[%v]\n", err)
+		}
+		return
+	}
+	f.sockets = append(f.sockets, socket)
+}
+
+func (f *jsonFormatter) Flush() {}
+
+func (f *jsonFormatter) Close() {
+	if f.stream {
+		return
+	}
+	if f.sockets == nil {
+		f.sockets = []jsonSocket{}
+	}
+	f.enc.SetIndent("", "  ")
+	if err := f.enc.Encode(f.sockets); err != nil {
+		fmt.Fprintf(os.Stderr, "write json error:[%v]\n", err)
+	}
+}
@@ -11,7 +11,11 @@ import (
 const (
 	version = "ss utility, 0.0.1"
 	usage   = "Usage:\tss [ OPTIONS ]\n" +
-		"\tss [ OPTIONS ] [ FILTER ]\n"
+		"\tss [ OPTIONS ] [ FILTER ]\n" +
+		"FILTER := [ state STATE-FILTER ] [ EXPRESSION ]\n" +
+		"EXPRESSION := src|dst HOSTCOND | sport|dport OP [:]PORT | not EXPRESSION |\n" +
+		"\tEXPRESSION and|or EXPRESSION | ( EXPRESSION )\n" +
+		"HOSTCOND := ADDR[/PREFIXLEN][:PORT] | /UNIX-PATH-GLOB | @UNIX-ABSTRACT-GLOB"
 )
 
 var (
@@ -21,33 +25,57 @@ var (
 	flagAll    = flag.Bool("a", false, "display all sockets")       // ok
 	flagListen = flag.Bool("l", false, "display listening sockets") // ok
 
-	flagExtended   = flag.Bool("e", false, "show detailed socket information") // ok
-	flagInfo       = flag.Bool("i", false, "show internal TCP information")    // ok
-	flagMemory     = flag.Bool("m", false, "show socket memory usage")         // ok
-	flagNotResolve = flag.Bool("n", false, "don't resolve service names")      // born to be
-	flagOption     = flag.Bool("o", false, "show timer information")           // ok
-	flagProcess    = flag.Bool("p", false, "show process using socket")        // ok
-	flagResolve    = flag.Bool("r", false, "resolve host names")               //
-	flagSummary    = flag.Bool("s", false, "show socket usage summary")        // ok
-
-	flagIPv4   = flag.Bool("4", false, "display only IP version 4 sockets") // ok
-	flagIPv6   = flag.Bool("6", false, "display only IP version 6 sockets") // ok
-	flagPacket = flag.Bool("0", false, "display PACKET sockets")            //
-	flagDCCP   = flag.Bool("d", false, "display only DCCP sockets")         //
-	flagSCTP   = flag.Bool("S", false, "display only SCTP sockets")         //
-	flagTCP    = flag.Bool("t", false, "display only TCP sockets")          // ok
-	flagUDP    = flag.Bool("u", false, "display only UDP sockets")          // ok
-	flagRAW    = flag.Bool("w", false, "display only RAW sockets")          // ok
-	flagUnix   = flag.Bool("x", false, "display only Unix domain sockets")  // ok
+	flagExtended   = flag.Bool("e", false, "show detailed socket information")                                 // ok
+	flagInfo       = flag.Bool("i", false, "show internal TCP information")                                    // ok
+	flagMemory     = flag.Bool("m", false, "show socket memory usage")                                         // ok
+	flagNotResolve = flag.Bool("n", false, "don't resolve service names")                                      // ok
+	flagOption     = flag.Bool("o", false, "show timer information")                                           // ok
+	flagProcess    = flag.Bool("p", false, "show process using socket")                                        // ok
+	flagResolve    = flag.Bool("r", false, "resolve host names")                                               // ok
+	flagSummary    = flag.Bool("s", false, "show socket usage summary")                                        // ok
+	flagKill       = flag.Bool("K", false, "forcibly close sockets, display what was closed")                  // ok
+	flagNetNS      = flag.String("N", "", "switch to the network namespace given by name, path, pid or inode") // ok
+	flagAllNetNS   = flag.Bool("all-netns", false, "display sockets of all network namespaces")                // ok
+	flagSort       = flag.String("sort", "", "sort by comma separated keys, a leading '-' reverses")           // ok
+	flagColumns    = flag.String("columns", "", "comma separated columns to display")                          // ok
+	flagOneline    = flag.Bool("O", false, "print each socket on a single line")                               // ok
+	flagJSON       = flag.Bool("json", false, "print sockets as one JSON document")                            // ok
+	flagNDJSON     = flag.Bool("ndjson", false, "print one JSON object per socket and line")                   // ok
+	flagWatch      = flag.Bool("watch", false, "print TCP and UDP sockets as they are destroyed")              // ok
+	flagPoll       = flag.Duration("poll", 0, "with -watch, poll every interval for changed sockets")          // ok
+	flagRate       = flag.Duration("rate", 0, "show the busiest TCP connections every interval")               // ok
+	flagTop        = flag.Int("top", 10, "number of connections shown by -rate, 0 for all")                    // ok
+	flagDiagnose   = flag.Bool("diagnose", false, "list TCP connections with problems and the reasons")        // ok
+	flagCapture    = flag.String("capture", "", "record the sock_diag answers and proc files to a directory")  // ok
+	flagReplay     = flag.String("replay", "", "read sockets from a directory written by -capture")            // ok
+	flagProcRoot   = flag.String("proc-root", "", "where proc is mounted, for processes and namespaces")       // ok
+
+	flagIPv4    = flag.Bool("4", false, "display only IP version 4 sockets")   // ok
+	flagIPv6    = flag.Bool("6", false, "display only IP version 6 sockets")   // ok
+	flagPacket  = flag.Bool("0", false, "display PACKET sockets")              // ok
+	flagNetlink = flag.Bool("netlink", false, "display only Netlink sockets")  // ok
+	flagDCCP    = flag.Bool("d", false, "display only DCCP sockets")           // ok
+	flagSCTP    = flag.Bool("S", false, "display only SCTP sockets")           // ok
+	flagTCP     = flag.Bool("t", false, "display only TCP sockets")            // ok
+	flagUDP     = flag.Bool("u", false, "display only UDP sockets")            // ok
+	flagUDPLite = flag.Bool("udplite", false, "display only UDP-Lite sockets") // ok
+	flagMPTCP   = flag.Bool("M", false, "display only MPTCP sockets")          // ok
+	flagRAW     = flag.Bool("w", false, "display only RAW sockets")            // ok
+	flagUnix    = flag.Bool("x", false, "display only Unix domain sockets")    // ok
 
 	newlineFlag bool
 
-	sis map[uint32]psss.SocketInfo
+	killClient *psss.Client
+
+	formatter   Formatter
+	resolver    *psss.Resolver
+	sortKeys    []psss.SortKey
+	showColumns []column
 )
 
 func main() {
 	flag.Parse()
-	if flag.NFlag() == 0 || *flagHelp {
+	if flag.NFlag() == 0 && flag.NArg() == 0 || *flagHelp {
 		fmt.Println(usage)
 		flag.PrintDefaults()
 		return
@@ -56,6 +84,25 @@ func main() {
 		fmt.Println(version)
 		return
 	}
+	if len(*flagProcRoot) > 0 {
+		psss.ProcRoot = *flagProcRoot
+	}
+	if len(*flagNetNS) > 0 {
+		ns, err := psss.ParseNetNS(*flagNetNS)
+		if err != nil {
+			fmt.Printf("open netns error:[%v]\n", err)
+			return
+		}
+		psss.NetNamespace = ns
+	}
+	if len(*flagCapture) > 0 || len(*flagReplay) > 0 {
+		if err := openCapture(); err != nil {
+			fmt.Printf("open capture error:[%v]\n", err)
+			return
+		}
+		defer psss.DiagTransport.Close()
+	}
+
 	if *flagSummary {
 		ShowSummary()
 		return
@@ -67,24 +114,50 @@ func main() {
 	if *flagListen {
 		psss.SsFilter = 1<<psss.SsLISTEN | 1<<psss.SsUNCONN
 	}
+	if flag.NArg() > 0 {
+		filter, err := psss.ParseSocketFilter(flag.Args())
+		if err != nil {
+			fmt.Printf("parse filter error:[%v]\n", err)
+			return
+		}
+		if filter.States != 0 {
+			psss.SsFilter = filter.States
+		}
+		psss.ExprFilter = filter
+	}
+	if psss.SsFilter == 0 && *flagDiagnose {
+		psss.SsFilter = psss.SsAllStates &^ (1<<psss.SsLISTEN | 1<<psss.SsUNCONN | 1<<psss.SsTIMEWAIT)
+	}
 	if psss.SsFilter == 0 {
 		psss.SsFilter = 1 << psss.SsESTAB
+		if *flagPacket || *flagNetlink {
+			// packet and most netlink sockets are never connected
+			psss.SsFilter |= 1 << psss.SsUNCONN
+		}
 	}
 
 	if *flagIPv4 {
 		psss.AfFilter |= 1 << unix.AF_INET
-		if !*flagTCP && !*flagUDP && !*flagRAW {
+		if !*flagTCP && !*flagUDP && !*flagRAW && !*flagSCTP && !*flagDCCP && !*flagUDPLite && !*flagMPTCP {
 			*flagTCP = true
 			*flagUDP = true
 			*flagRAW = true
+			*flagSCTP = true
+			*flagDCCP = true
+			*flagUDPLite = true
+			*flagMPTCP = true
 		}
 	}
 	if *flagIPv6 {
 		psss.AfFilter |= 1 << unix.AF_INET6
-		if !*flagTCP && !*flagUDP && !*flagRAW {
+		if !*flagTCP && !*flagUDP && !*flagRAW && !*flagSCTP && !*flagDCCP && !*flagUDPLite && !*flagMPTCP {
 			*flagTCP = true
 			*flagUDP = true
 			*flagRAW = true
+			*flagSCTP = true
+			*flagDCCP = true
+			*flagUDPLite = true
+			*flagMPTCP = true
 		}
 	}
 	if psss.AfFilter == 0 {
@@ -100,10 +173,30 @@ func main() {
 	if *flagRAW {
 		psss.ProtocalFilter |= psss.ProtocalRAW
 	}
+	if *flagSCTP {
+		psss.ProtocalFilter |= psss.ProtocalSCTP
+	}
+	if *flagDCCP {
+		psss.ProtocalFilter |= psss.ProtocalDCCP
+	}
+	if *flagUDPLite {
+		psss.ProtocalFilter |= psss.ProtocalUDPLite
+	}
+	if *flagMPTCP {
+		psss.ProtocalFilter |= psss.ProtocalMPTCP
+	}
 	if *flagUnix {
 		psss.AfFilter |= 1 << unix.AF_UNIX
 		psss.ProtocalFilter |= psss.ProtocalUnix
 	}
+	if *flagPacket {
+		psss.AfFilter |= 1 << unix.AF_PACKET
+		psss.ProtocalFilter |= psss.ProtocalPacket
+	}
+	if *flagNetlink {
+		psss.AfFilter |= 1 << unix.AF_NETLINK
+		psss.ProtocalFilter |= psss.ProtocalNetlink
+	}
 	if psss.ProtocalFilter == 0 {
 		psss.ProtocalFilter |= psss.ProtocalMax - 1
 	}
@@ -112,18 +205,77 @@ func main() {
 		psss.FlagInfo = true
 	}
 
+	if *flagDiagnose {
+		// the report names the processes to look at
+		*flagProcess = true
+	}
+
 	if *flagMemory {
 		psss.FlagMemory = true
 	}
 
+	if *flagExtended {
+		psss.FlagExtended = true
+	}
+
 	if *flagExtended || *flagOption || *flagMemory || *flagInfo {
 		newlineFlag = true
 	}
 
+	if len(*flagSort) > 0 {
+		keys, err := psss.ParseSortKeys(*flagSort)
+		if err != nil {
+			fmt.Printf("parse sort error:[%v]\n", err)
+			return
+		}
+		sortKeys = keys
+	}
+	selected, err := selectColumns()
+	if err != nil {
+		fmt.Printf("parse columns error:[%v]\n", err)
+		return
+	}
+	showColumns = selected
+	formatter = newFormatter()
+	if !*flagNotResolve || *flagResolve {
+		resolver = psss.NewResolver()
+		if !*flagNotResolve {
+			if err = resolver.LoadServices(""); err != nil {
+				fmt.Printf("load services error:[%v]\n", err)
+			}
+		}
+	}
+	defer formatter.Close()
+
 	if *flagProcess {
 		psss.FlagProcess = true
-		psss.GetProcInfo(true)
+		psss.GetProcInfo(nil, true)
+	}
+
+	if *flagAllNetNS {
+		nss, err := psss.ListNetNS()
+		if err != nil {
+			fmt.Printf("list netns error:[%v]\n", err)
+			return
+		}
+		for _, ns := range nss {
+			psss.NetNamespace = ns
+			SocketShow()
+		}
+		return
 	}
 
+	if *flagRate > 0 {
+		RateShow()
+		return
+	}
+	if *flagDiagnose {
+		Diagnose()
+		return
+	}
+	if *flagWatch {
+		Watch()
+		return
+	}
 	SocketShow()
 }
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/buck119br/psss/psss"
+	"golang.org/x/sys/unix"
+)
+
+func readTCP(c *psss.Client) (sis []psss.SocketInfo, err error) {
+	for _, af := range []int{unix.AF_INET, unix.AF_INET6} {
+		if psss.AfFilter&(1<<af) == 0 {
+			continue
+		}
+		part, err := c.InetRead(psss.ProtocalTCP, af)
+		if errors.Is(err, psss.ErrTruncatedMessage) {
+			fmt.Fprintf(os.Stderr, "read sockets warning:[%v]\n", err)
+		} else if err != nil {
+			return nil, err
+		}
+		sis = append(sis, part...)
+	}
+	return sis, nil
+}
+
+// RateShow prints the --top busiest TCP connections every --rate interval
+// until interrupted.
+func RateShow() {
+	c := psss.NewClient()
+	c.States = psss.SsFilter
+	c.Filter = psss.ExprFilter
+	c.Info = true
+	c.Process = *flagProcess
+	c.NetNS = psss.NetNamespace
+	c.Transport = psss.DiagTransport
+	defer c.Close()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	prev, err := readTCP(c)
+	if err != nil {
+		fmt.Printf("read sockets error:[%v]\n", err)
+		return
+	}
+	last := time.Now()
+	ticker := time.NewTicker(*flagRate)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-signals:
+			return
+		case now := <-ticker.C:
+			cur, err := readTCP(c)
+			if err != nil {
+				fmt.Printf("read sockets error:[%v]\n", err)
+				return
+			}
+			rates := psss.ComputeRates(prev, cur, now.Sub(last))
+			psss.SortRatesByBandwidth(rates)
+			showRates(now, rates)
+			prev, last = cur, now
+		}
+	}
+}
+
+func showRates(now time.Time, rates []psss.SocketRate) {
+	total := len(rates)
+	if *flagTop > 0 && len(rates) > *flagTop {
+		rates = rates[:*flagTop]
+	}
+	if *flagResolve && resolver != nil {
+		addrs := make([]netip.Addr, 0, 2*len(rates))
+		for i := range rates {
+			addrs = append(addrs, rates[i].Info.LocalAddr.Addr(), rates[i].Info.RemoteAddr.Addr())
+		}
+		resolver.Prefetch(addrs)
+	}
+	headers := []string{"LocalAddress:Port", "RemoteAddress:Port", "TX", "RX", "Retrans", "RTT", "RTT-Delta"}
+	if *flagProcess {
+		headers = append(headers, "Users")
+	}
+	widths := make([]int, len(headers))
+	for j := range headers {
+		widths[j] = len(headers[j])
+	}
+	cells := make([][]string, len(rates))
+	for i := range rates {
+		r := &rates[i]
+		cells[i] = []string{
+			resolver.AddrString(r.Info.LocalAddr, r.Info.Protocal, *flagResolve, !*flagNotResolve),
+			resolver.AddrString(r.Info.RemoteAddr, r.Info.Protocal, *flagResolve, !*flagNotResolve),
+			psss.BwToStr(r.TxBps) + "bps",
+			psss.BwToStr(r.RxBps) + "bps",
+			fmt.Sprintf("%.2f%%", r.RetransRatio*100),
+			fmt.Sprintf("%.3f", float64(r.Info.TCPInfo.Rtt)/1000),
+			fmt.Sprintf("%+.3f", float64(r.RttDelta)/1000),
+		}
+		if *flagProcess && len(r.Info.Owners) > 0 {
+			cells[i] = append(cells[i], r.Info.OwnersString())
+		}
+		for j := range cells[i] {
+			if widths[j] < len(cells[i][j]) {
+				widths[j] = len(cells[i][j])
+			}
+		}
+	}
+	fmt.Printf("%s top %d of %d connections\n", now.Format("15:04:05"), len(rates), total)
+	for j := range headers {
+		printCell(os.Stdout, headers[j], widths, j)
+	}
+	fmt.Printf("\n")
+	for i := range cells {
+		for j := range cells[i] {
+			printCell(os.Stdout, cells[i][j], widths, j)
+		}
+		fmt.Printf("\n")
+	}
+	fmt.Printf("\n")
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/buck119br/psss/psss"
+)
+
+// Watch prints socket events until interrupted, the destroy notifications
+// of the kernel by default or the differences between reads every --poll.
+func Watch() {
+	c := psss.NewClient()
+	c.States = psss.SsFilter
+	c.Filter = psss.ExprFilter
+	c.Info = *flagInfo
+	c.Memory = *flagMemory
+	c.Extended = *flagExtended
+	c.Process = *flagProcess
+	c.NetNS = psss.NetNamespace
+	c.Transport = psss.DiagTransport
+	defer c.Close()
+
+	stop := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		<-signals
+		close(stop)
+	}()
+
+	fn := func(ev *psss.SocketEvent) bool {
+		if ev.Type == psss.SocketEventLost {
+			fmt.Fprintf(os.Stderr, "%s events lost, the receive buffer overflowed\n", ev.Time.Format("15:04:05"))
+			return true
+		}
+		row := socketRow{
+			af:    ev.Family,
+			si:    ev.Info,
+			event: psss.SocketEventName[ev.Type],
+			time:  ev.Time,
+		}
+		if ev.Type == psss.SocketEventState {
+			info := psss.SocketInfo{Status: ev.OldStatus}
+			row.oldState = info.StateString()
+		}
+		formatter.Row(&row)
+		return true
+	}
+	var err error
+	if *flagPoll > 0 {
+		var queries []psss.SocketQuery
+		for _, q := range socketQueries {
+			if q.enabled() {
+				queries = append(queries, psss.SocketQuery{Protocal: q.protocal, Af: q.af})
+			}
+		}
+		err = c.WatchPoll(queries, *flagPoll, stop, fn)
+	} else {
+		err = c.WatchDestroy(psss.DestroyGroups(psss.ProtocalFilter, psss.AfFilter), stop, fn)
+	}
+	if err != nil {
+		fmt.Printf("watch error:[%v]\n", err)
+	}
+}
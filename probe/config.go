@@ -2,14 +2,23 @@ package probe
 
 import (
 	"fmt"
+	"path/filepath"
 
 	"github.com/BurntSushi/toml"
+	"github.com/buck119br/psss/psss"
 )
 
 var GConfig *ProbeConfig
 
 type ProbeConfig struct {
 	SamplingInterval uint64
+	// where proc and sys of the probed host are mounted, /proc and /sys by
+	// default
+	ProcRoot string
+	SysRoot  string
+	// the process network devices and mounts are read for, self by default
+	// and 1, the host, when ProcRoot is a proc mounted from elsewhere
+	ProcPid string
 
 	IO struct {
 		NIC struct {
@@ -58,5 +67,17 @@ func (pc *ProbeConfig) Check() error {
 	if pc.SamplingInterval < 5 {
 		return fmt.Errorf("too fast sampling")
 	}
+	if len(pc.ProcRoot) == 0 {
+		pc.ProcRoot = psss.DefaultProcRoot
+	}
+	if len(pc.SysRoot) == 0 {
+		pc.SysRoot = psss.DefaultSysRoot
+	}
+	if len(pc.ProcPid) == 0 {
+		pc.ProcPid = psss.DefaultProcPid
+		if filepath.Clean(pc.ProcRoot) != psss.DefaultProcRoot {
+			pc.ProcPid = "1"
+		}
+	}
 	return nil
 }
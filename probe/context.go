@@ -1,11 +1,7 @@
 package probe
 
 import (
-	"fmt"
-	"io/ioutil"
 	"runtime/debug"
-	"strconv"
-	"strings"
 	"syscall"
 	"time"
 
@@ -45,7 +41,7 @@ func (pc *ProbeContext) GetSystemUptime() error {
 	}()
 
 	pc.Uptime = new(psss.Uptime)
-	return pc.Uptime.Get()
+	return pc.Uptime.GetFrom(GConfig.ProcRoot)
 }
 
 func (pc *ProbeContext) GetSystemStat() error {
@@ -57,7 +53,7 @@ func (pc *ProbeContext) GetSystemStat() error {
 	}()
 
 	pc.SystemStat = new(psss.SystemStat)
-	return pc.SystemStat.Get()
+	return pc.SystemStat.GetFrom(GConfig.ProcRoot)
 }
 
 func (pc *ProbeContext) GetMemoryInfo() error {
@@ -69,7 +65,7 @@ func (pc *ProbeContext) GetMemoryInfo() error {
 	}()
 
 	pc.MemoryInfo = new(psss.MemoryInfo)
-	return pc.MemoryInfo.Get()
+	return pc.MemoryInfo.GetFrom(GConfig.ProcRoot)
 }
 
 func (pc *ProbeContext) GetNetDevs() error {
@@ -81,7 +77,7 @@ func (pc *ProbeContext) GetNetDevs() error {
 	}()
 
 	pc.NetDevs = psss.NewNetDevs()
-	return pc.NetDevs.Get()
+	return pc.NetDevs.GetFrom(GConfig.ProcRoot)
 }
 
 func (pc *ProbeContext) GetMountInfo() error {
@@ -93,18 +89,17 @@ func (pc *ProbeContext) GetMountInfo() error {
 	}()
 
 	mis := psss.NewMountInfos()
-	err := mis.Get()
+	err := mis.GetFrom(GConfig.ProcRoot)
 	if err != nil {
 		return err
 	}
 	dss := psss.NewDiskStats()
-	if err = dss.Get(); err != nil {
+	if err = dss.GetFrom(GConfig.ProcRoot); err != nil {
 		return err
 	}
 
 	pc.MountInfo = make(map[string]*extMountInfo)
 	var ok bool
-	var raw []byte
 	for _, mi := range mis {
 		if _, ok = GConfig.FileSystem.MountInfo.MountPointSet[mi.MountPoint]; !ok {
 			continue
@@ -121,14 +116,10 @@ func (pc *ProbeContext) GetMountInfo() error {
 				continue
 			}
 			emi.DiskStat = ds
-			if raw, err = ioutil.ReadFile(fmt.Sprintf("/sys/block/%s/queue/hw_sector_size", emi.DiskStat.Name)); err != nil {
+			if emi.HWSectorSize, err = emi.DiskStat.HWSectorSizeFrom(GConfig.SysRoot); err != nil {
 				logger.Errorf("get sector size error:[%v]", err)
 				continue
 			}
-			if emi.HWSectorSize, err = strconv.ParseUint(strings.Replace(string(raw), "\n", "", -1), 10, 64); err != nil {
-				logger.Errorf("parse sector size error:[%v]", err)
-				continue
-			}
 		}
 		pc.MountInfo[emi.MountInfo.MountPoint] = emi
 	}
@@ -172,7 +163,7 @@ func (pc *ProbeContext) Sample() error {
 		logger.Errorf("get system stat error:[%v]", err)
 	}
 	if GConfig.Process.Switch {
-		prev.ProcInfo = psss.GetProcInfo(GConfig.Process.ProcNameSet, false)
+		prev.ProcInfo = psss.GetProcInfoFrom(GConfig.ProcRoot, GConfig.Process.ProcNameSet, false)
 	}
 	if GConfig.IO.NIC.Switch {
 		if err = prev.GetNetDevs(); err != nil {
@@ -207,7 +198,7 @@ func (pc *ProbeContext) Sample() error {
 			}
 		}
 		if GConfig.Process.Switch {
-			pc.ProcInfo = psss.GetProcInfo(GConfig.Process.ProcNameSet, false)
+			pc.ProcInfo = psss.GetProcInfoFrom(GConfig.ProcRoot, GConfig.Process.ProcNameSet, false)
 		}
 
 		// the following modules are costly
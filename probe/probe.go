@@ -5,6 +5,8 @@ import (
 	"runtime/debug"
 	"sync"
 	"time"
+
+	"github.com/buck119br/psss/psss"
 )
 
 var GProbe Probe = newProbe()
@@ -91,6 +93,7 @@ func (p *probe) Init(config *ProbeConfig) error {
 	if err != nil {
 		return err
 	}
+	psss.ProcPid = GConfig.ProcPid
 
 	p.ctx = NewProbeContext()
 
@@ -15,13 +15,14 @@ var (
 	AfFilter       uint64
 	ProtocalFilter uint64
 	SsFilter       uint32
-
-	FlagProcess bool
-	FlagInfo    bool
-	FlagMemory  bool
-
-	MaxLocalAddrLength  int
-	MaxRemoteAddrLength int
+	ExprFilter     *SocketFilter
+	NetNamespace   *NetNS
+	DiagTransport  Transport
+
+	FlagProcess  bool
+	FlagInfo     bool
+	FlagMemory   bool
+	FlagExtended bool
 )
 
 var (
@@ -32,6 +33,15 @@ var (
 
 var (
 	ErrorDone = fmt.Errorf("Done")
+
+	// matched by errors.Is against the errors of a Client
+	ErrPermission        = fmt.Errorf("permission denied")
+	ErrUnsupportedFamily = fmt.Errorf("address family or protocol not supported")
+	// the kernel lost or changed sockets while dumping, a read may retry
+	ErrDumpInterrupted = fmt.Errorf("dump interrupted")
+	// a message of a dump was too short to decode, the reads return the
+	// sockets of the other messages along with it
+	ErrTruncatedMessage = fmt.Errorf("truncated message")
 )
 
 var (
@@ -40,10 +50,12 @@ var (
 
 var (
 	// channel
-	SocketInfoChan chan SocketInfo
-	ProcInfoChan   chan *ProcInfo
+	ProcInfoChan chan *ProcInfo
 
-	GlobalProcFds map[string]map[int]map[uint32]Fd
+	// GlobalSocketOwners maps a socket inode to every process holding it,
+	// it is replaced as a whole by each ScanProcFS with fds.
+	GlobalSocketOwners map[uint32][]SocketOwner
+	scanSocketOwners   map[uint32][]SocketOwner
 
 	bytesCounter int
 )
@@ -55,15 +67,9 @@ func init() {
 		panic(err)
 	}
 
-	SocketInfoChan = make(chan SocketInfo)
 	ProcInfoChan = make(chan *ProcInfo)
 
-	GlobalProcFds = make(map[string]map[int]map[uint32]Fd)
+	GlobalSocketOwners = make(map[uint32][]SocketOwner)
 
 	archInit()
 }
-
-func AddrLengthInit() {
-	MaxLocalAddrLength = 17
-	MaxRemoteAddrLength = 18
-}
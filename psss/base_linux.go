@@ -5,26 +5,28 @@ package psss
 import (
 	"bytes"
 	"syscall"
+)
 
-	"golang.org/x/sys/unix"
+const (
+	DefaultProcRoot = "/proc"
+	DefaultSysRoot  = "/sys"
+	DefaultProcPid  = "self"
 )
 
-const ProcRoot = "/proc"
+// ProcRoot and SysRoot are where proc and sys are mounted, used by the
+// readers without a root of their own. ProcPid is the process whose
+// per-process files, like net/dev and mountinfo, are read below any root:
+// self is the reader, 1 is the host when its proc is mounted into a
+// container. The sockets of a Client follow its NetNS instead.
+var (
+	ProcRoot = DefaultProcRoot
+	SysRoot  = DefaultSysRoot
+	ProcPid  = DefaultProcPid
+)
 
 var (
 	// buffer
-	sockDiagMsgBuffer   []byte
-	unDiagRequestBuffer []byte
-	inDiagRequestBuffer []byte
-	fileContentBuffer   *bytes.Buffer
-
-	sockAddrNl  unix.SockaddrNetlink
-	nlAttr      unix.NlAttr
-	unDiagReq   UnixDiagRequest
-	unDiagMsg   UnixDiagMessage
-	unDiagRQlen UnixDiagRQlen
-	inDiagReq   InetDiagRequest
-	inDiagMsg   InetDiagMessage
+	fileContentBuffer *bytes.Buffer
 
 	procDirentReader *DirentReader
 	fdDirentReader   *DirentReader
@@ -32,9 +34,6 @@ var (
 )
 
 func archInit() {
-	sockDiagMsgBuffer = make([]byte, OSPageSize)
-	unDiagRequestBuffer = make([]byte, SizeOfUnixDiagRequest)
-	inDiagRequestBuffer = make([]byte, SizeOfInetDiagRequest)
 	fileContentBuffer = bytes.NewBuffer(make([]byte, OSPageSize))
 
 	procDirentReader = NewDirentReader()
@@ -0,0 +1,335 @@
+// +build linux
+
+package psss
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// Client reads sockets through its own netlink socket and buffers, so that
+// several clients can be used from different goroutines at the same time.
+// A single Client serializes its own requests.
+type Client struct {
+	States   uint32        // bitmask of Ss* states to dump
+	Filter   *SocketFilter // optional filter expression
+	Info     bool          // request internal TCP information
+	Memory   bool          // request socket memory usage
+	Extended bool          // request TOS, TCLASS and class id
+	Process  bool          // relate sockets to processes
+	NetNS    *NetNS        // namespace to read, nil for the own one
+	// ProcRoot is where proc is mounted, for the fallbacks. Its net files
+	// follow NetNS like the netlink socket does, not the owner of the mount.
+	ProcRoot string
+	// Transport replaces the netlink socket in NetNS, for instance to replay
+	// a capture. It is not closed by Close and must not be shared by clients
+	// used at the same time.
+	Transport Transport
+
+	mutex   sync.Mutex
+	netlink Transport
+}
+
+func NewClient() *Client {
+	c := new(Client)
+	c.States = SsAllStates
+	c.ProcRoot = ProcRoot
+	return c
+}
+
+func newClientFromFlags() *Client {
+	c := NewClient()
+	c.States = SsFilter
+	c.Filter = ExprFilter
+	c.Info = FlagInfo
+	c.Memory = FlagMemory
+	c.Extended = FlagExtended
+	c.Process = FlagProcess
+	c.NetNS = NetNamespace
+	c.Transport = DiagTransport
+	return c
+}
+
+func (c *Client) Close() (err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.netlink != nil {
+		err = c.netlink.Close()
+		c.netlink = nil
+	}
+	return err
+}
+
+func (c *Client) transport() Transport {
+	if c.Transport != nil {
+		return c.Transport
+	}
+	if c.netlink == nil {
+		c.netlink = NewNetlinkTransport(c.NetNS)
+	}
+	return c.netlink
+}
+
+// SocketQuery selects the sockets of a single dump. Af is ignored for
+// ProtocalUnix, ProtocalPacket and ProtocalNetlink.
+type SocketQuery struct {
+	Protocal int
+	Af       int
+}
+
+// dump sends a sock_diag dump request and hands every answered message to fn
+// until fn returns false. Errors of the kernel are returned as *NetlinkError.
+// A dump that lost messages to a full receive buffer, or that the kernel
+// marked inconsistent, returns ErrDumpInterrupted after the messages it got.
+func (c *Client) dump(request []byte, fn func(data []byte) bool) (err error) {
+	t := c.transport()
+	if err = t.Send(request); err != nil {
+		return wrapErrno(err)
+	}
+	interrupted := false
+	for {
+		data, err := t.Recv()
+		switch err {
+		case nil:
+		case unix.EINTR:
+			continue
+		case unix.ENOBUFS:
+			// the rest of the dump is unusable, start over with a new socket
+			t.Reset()
+			return ErrDumpInterrupted
+		default:
+			t.Reset()
+			return wrapErrno(err)
+		}
+		raw, err := syscall.ParseNetlinkMessage(data)
+		if err != nil {
+			t.Reset()
+			return fmt.Errorf("parse netlink message error:[%v]", err)
+		}
+		for i := range raw {
+			if raw[i].Header.Flags&unix.NLM_F_DUMP_INTR != 0 {
+				interrupted = true
+			}
+			switch raw[i].Header.Type {
+			case unix.NLMSG_DONE:
+				if err = netlinkError(&raw[i]); err == nil && interrupted {
+					err = ErrDumpInterrupted
+				}
+				return err
+			case unix.NLMSG_ERROR:
+				if err = netlinkError(&raw[i]); err == nil {
+					err = fmt.Errorf("unexpected netlink ack")
+				}
+				// the kernel ends a dump with its error
+				return err
+			}
+			if !fn(raw[i].Data) {
+				// the unread part of the dump would be answered to the next request
+				t.Reset()
+				return nil
+			}
+		}
+	}
+}
+
+func inetProtocol(protocal int) (int, error) {
+	switch protocal {
+	case ProtocalTCP:
+		return unix.IPPROTO_TCP, nil
+	case ProtocalUDP:
+		return unix.IPPROTO_UDP, nil
+	case ProtocalUDPLite:
+		return unix.IPPROTO_UDPLITE, nil
+	case ProtocalRAW:
+		return unix.IPPROTO_RAW, nil
+	case ProtocalSCTP:
+		return unix.IPPROTO_SCTP, nil
+	case ProtocalDCCP:
+		return unix.IPPROTO_DCCP, nil
+	case ProtocalMPTCP:
+		return unix.IPPROTO_MPTCP, nil
+	}
+	return 0, fmt.Errorf("invalid protocal:[%d]", protocal)
+}
+
+// ForEachSocket streams the sockets selected by q to fn as they are parsed,
+// stopping as soon as fn returns false. si is reused between calls, so fn
+// has to copy it to keep it. An interrupted dump is started over as long as
+// nothing was handed to fn yet. When sock_diag does not support the request,
+// or refuses it, and nothing was handed to fn, the sockets are read from the
+// proc files instead; SocketInfo.Source tells which one answered. Messages
+// too short to decode are skipped, the first one is reported as
+// ErrTruncatedMessage once the dump is done.
+func (c *Client) ForEachSocket(q SocketQuery, fn func(si *SocketInfo) bool) (err error) {
+	var (
+		request []byte
+		parse   func(data []byte, si *SocketInfo) error
+	)
+	switch q.Protocal {
+	case ProtocalUnix:
+		request = newUnixDiagRequest(c.States,
+			UDIAG_SHOW_NAME|UDIAG_SHOW_VFS|UDIAG_SHOW_PEER|UDIAG_SHOW_ICONS|UDIAG_SHOW_RQLEN|UDIAG_SHOW_MEMINFO)
+		parse = parseUnixDiagMsg
+	case ProtocalPacket:
+		if c.States&(1<<SsUNCONN) == 0 {
+			// packet sockets have no state, they are always unconnected
+			return nil
+		}
+		request = newPacketDiagRequest(PACKET_SHOW_INFO | PACKET_SHOW_MCLIST | PACKET_SHOW_RING_CFG | PACKET_SHOW_FANOUT | PACKET_SHOW_MEMINFO | PACKET_SHOW_FILTER)
+		parse = parsePacketDiagMsg
+	case ProtocalNetlink:
+		request = newNetlinkDiagRequest(NDIAG_SHOW_MEMINFO | NDIAG_SHOW_GROUPS | NDIAG_SHOW_FLAGS)
+		parse = parseNetlinkDiagMsg
+	default:
+		ipproto, err := inetProtocol(q.Protocal)
+		if err != nil {
+			return err
+		}
+		states := c.States
+		if q.Protocal == ProtocalDCCP {
+			states = dccpStates(states)
+		}
+		request = newInetDiagRequest(uint8(q.Af), ipproto, inetDiagExts(q.Protocal, c.Info, c.Memory, c.Extended), states, c.Filter.Bytecode())
+		parse = parseInetDiagMsg
+	}
+
+	if c.NetNS != nil {
+		deliver := fn
+		fn = func(si *SocketInfo) bool {
+			si.NetNS = c.NetNS.Name
+			return deliver(si)
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	si := NewSocketInfo()
+	delivered := false
+	var parseErr error
+	handle := func(data []byte) bool {
+		si.Reset()
+		si.Protocal = q.Protocal
+		si.Source = SourceNetlink
+		if err := parse(data, si); err != nil {
+			// skip it, the rest of the dump is still good
+			if parseErr == nil {
+				parseErr = err
+			}
+			return true
+		}
+		if q.Protocal == ProtocalDCCP {
+			si.fixDCCPState()
+		}
+		if (q.Protocal == ProtocalSCTP || q.Protocal == ProtocalDCCP || q.Protocal == ProtocalUnix) && !c.match(si) {
+			// SCTP associations are dumped regardless of the state and bytecode
+			// filters, DCCP has to drop the states only added for it and unix
+			// dumps have no bytecode at all
+			return true
+		}
+		if q.Protocal == ProtocalNetlink && c.States&(1<<si.Status) == 0 {
+			return true
+		}
+		if c.Process {
+			si.SetUpRelation()
+		}
+		delivered = true
+		return fn(si)
+	}
+	for try := 1; ; try++ {
+		parseErr = nil
+		err = c.dump(request, handle)
+		if err != ErrDumpInterrupted || delivered || try >= dumpRetries {
+			break
+		}
+	}
+	if err == nil && parseErr != nil {
+		return parseErr
+	}
+	if err == nil || delivered || !canReadProc(err) {
+		return err
+	}
+
+	proc := func(si *SocketInfo) bool {
+		si.Source = SourceProc
+		return fn(si)
+	}
+	var procErr error
+	switch q.Protocal {
+	case ProtocalUnix:
+		procErr = c.unixReadProc(proc)
+	case ProtocalSCTP:
+		procErr = c.sctpReadProc(q.Af, proc)
+	case ProtocalPacket:
+		procErr = c.packetReadProc(proc)
+	case ProtocalNetlink:
+		procErr = c.netlinkReadProc(proc)
+	case ProtocalMPTCP, ProtocalDCCP:
+		// there is no proc file for MPTCP or DCCP
+		return err
+	default:
+		procErr = c.inetReadProc(q.Protocal, q.Af, proc)
+	}
+	if procErr != nil {
+		return procError(err, procErr)
+	}
+	return nil
+}
+
+func (c *Client) match(si *SocketInfo) bool {
+	if c.States&(1<<si.Status) == 0 {
+		return false
+	}
+	return c.Filter == nil || c.Filter.Match(si)
+}
+
+// read collects the sockets of q, reading them again when the dump was
+// interrupted after some had been collected already.
+func (c *Client) read(q SocketQuery) (sis []SocketInfo, err error) {
+	for try := 1; try <= dumpRetries; try++ {
+		sis = sis[:0]
+		err = c.ForEachSocket(q, func(si *SocketInfo) bool {
+			sis = append(sis, *si)
+			return true
+		})
+		if err != ErrDumpInterrupted {
+			break
+		}
+	}
+	return sis, err
+}
+
+func (c *Client) InetRead(protocal, af int) ([]SocketInfo, error) {
+	return c.read(SocketQuery{Protocal: protocal, Af: af})
+}
+
+// UnixRead also resolves the peers of the sockets, see resolveUnixPeers. Like
+// the other reads it returns the sockets it got along with
+// ErrTruncatedMessage.
+func (c *Client) UnixRead() (sis []SocketInfo, err error) {
+	if sis, err = c.read(SocketQuery{Protocal: ProtocalUnix, Af: unix.AF_UNIX}); err != nil && !errors.Is(err, ErrTruncatedMessage) {
+		return nil, err
+	}
+	resolveUnixPeers(sis, c.Process)
+	return sis, err
+}
+
+func (c *Client) PacketRead() ([]SocketInfo, error) {
+	return c.read(SocketQuery{Protocal: ProtocalPacket, Af: unix.AF_PACKET})
+}
+
+func (c *Client) NetlinkRead() ([]SocketInfo, error) {
+	return c.read(SocketQuery{Protocal: ProtocalNetlink, Af: unix.AF_NETLINK})
+}
+
+// ForEachSocket streams sockets according to the package level filters and
+// flags, see Client.ForEachSocket.
+func ForEachSocket(q SocketQuery, fn func(si *SocketInfo) bool) error {
+	c := newClientFromFlags()
+	defer c.Close()
+	return c.ForEachSocket(q, fn)
+}
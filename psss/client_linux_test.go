@@ -0,0 +1,267 @@
+// +build linux
+
+package psss
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const procTCPLines = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 0100007F:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 4242 1 0000000000000000 100 0 0 10 0
+`
+
+// newProcClient returns a Client whose sock_diag requests are all refused as
+// unsupported, with the proc files written below a temporary ProcRoot.
+func newProcClient(t *testing.T, files map[string]string) *Client {
+	root := t.TempDir()
+	for key, content := range files {
+		path := filepath.Join(root, procFilePath[key])
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	transport, err := NewReplayTransport(strings.NewReader(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient()
+	c.ProcRoot = root
+	c.Transport = transport
+	return c
+}
+
+func TestForEachSocketProcFallback(t *testing.T) {
+	c := newProcClient(t, map[string]string{"TCP4": procTCPLines})
+	var sis []SocketInfo
+	err := c.ForEachSocket(SocketQuery{Protocal: ProtocalTCP, Af: unix.AF_INET}, func(si *SocketInfo) bool {
+		sis = append(sis, *si)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("ForEachSocket: %v", err)
+	}
+	if len(sis) != 1 {
+		t.Fatalf("got %d sockets, want 1", len(sis))
+	}
+	si := sis[0]
+	if si.Source != SourceProc {
+		t.Errorf("Source = %v, want SourceProc", si.Source)
+	}
+	if got := si.LocalAddr.AddrPort.String(); got != "127.0.0.1:8080" {
+		t.Errorf("LocalAddr = %s, want 127.0.0.1:8080", got)
+	}
+	if si.Status != SsLISTEN || si.UID != 1000 || si.Inode != 4242 {
+		t.Errorf("Status %d UID %d Inode %d, want %d 1000 4242", si.Status, si.UID, si.Inode, SsLISTEN)
+	}
+}
+
+func TestForEachSocketNoProcFile(t *testing.T) {
+	c := newProcClient(t, nil)
+	for _, protocal := range []int{ProtocalDCCP, ProtocalMPTCP, ProtocalTCP} {
+		called := false
+		err := c.ForEachSocket(SocketQuery{Protocal: protocal, Af: unix.AF_INET}, func(si *SocketInfo) bool {
+			called = true
+			return true
+		})
+		// the netlink error tells more than a proc file that is not there
+		if !errors.Is(err, ErrUnsupportedFamily) {
+			t.Errorf("protocal %d: got error %v, want ErrUnsupportedFamily", protocal, err)
+		}
+		if called {
+			t.Errorf("protocal %d: got sockets without netlink or proc file", protocal)
+		}
+	}
+}
+
+// stubTransport answers every request with the same datagrams.
+type stubTransport struct {
+	answer [][]byte
+	pos    int
+}
+
+func (t *stubTransport) Send(request []byte) error {
+	t.pos = 0
+	return nil
+}
+
+func (t *stubTransport) Recv() ([]byte, error) {
+	if t.pos >= len(t.answer) {
+		return nil, unix.EIO
+	}
+	t.pos++
+	return t.answer[t.pos-1], nil
+}
+
+func (t *stubTransport) Reset() error { return nil }
+func (t *stubTransport) Close() error { return nil }
+
+func netlinkMessage(typ uint16, data []byte) []byte {
+	msg := make([]byte, unix.NLMSG_HDRLEN+rtaAlign(len(data)))
+	*(*unix.NlMsghdr)(unsafe.Pointer(&msg[0])) = unix.NlMsghdr{
+		Len:   uint32(unix.NLMSG_HDRLEN + len(data)),
+		Type:  typ,
+		Flags: unix.NLM_F_MULTI,
+	}
+	copy(msg[unix.NLMSG_HDRLEN:], data)
+	return msg
+}
+
+func TestForEachSocketTruncatedMessage(t *testing.T) {
+	var msg InetDiagMessage
+	msg.IdiagFamily = unix.AF_INET
+	msg.IdiagState = SsLISTEN
+	msg.ID.IdiagSport = ntohs(80)
+	msg.ID.IdiagSrc[0] = 0x0100007f
+	msg.IdiagInode = 7
+	full := (*[SizeOfInetDiagMsg]byte)(unsafe.Pointer(&msg))[:]
+
+	var datagram []byte
+	datagram = append(datagram, netlinkMessage(SOCK_DIAG_BY_FAMILY, full[:10])...)
+	datagram = append(datagram, netlinkMessage(SOCK_DIAG_BY_FAMILY, full)...)
+	datagram = append(datagram, netlinkMessage(unix.NLMSG_DONE, make([]byte, 4))...)
+
+	c := NewClient()
+	c.Transport = &stubTransport{answer: [][]byte{datagram}}
+	// the reads keep the sockets of the other messages
+	sis, err := c.InetRead(ProtocalTCP, unix.AF_INET)
+	if !errors.Is(err, ErrTruncatedMessage) {
+		t.Errorf("got error %v, want the truncated message reported", err)
+	}
+	if len(sis) != 1 {
+		t.Fatalf("got %d sockets, want the one after the truncated message", len(sis))
+	}
+	if got := sis[0].LocalAddr.AddrPort.String(); got != "127.0.0.1:80" || sis[0].Inode != 7 {
+		t.Errorf("got %s inode %d, want 127.0.0.1:80 inode 7", got, sis[0].Inode)
+	}
+}
+
+func TestForEachSocketMalformedProcLines(t *testing.T) {
+	c := newProcClient(t, map[string]string{
+		"TCP4": procTCPLines + "\n" +
+			"   1: 0100007F:1F90\n" +
+			"   2: 0100007F:1F91 00000000:0000 0A 00000000 00:00000000 00000000  1000        0 4243 1 0000000000000000\n",
+		"Unix": "Num       RefCount Protocol Flags    Type St Inode Path\n" +
+			"0000000000000000: 00000002 00000000 00000000 0001 00 100\n" +
+			"0000000000000000: 00000002 00000000 00000000 0001 09 101\n" +
+			"0000000000000000: 00000002 00000000 00000000 0002 01 102 @ok\n",
+	})
+	for _, tt := range []struct {
+		q     SocketQuery
+		inode uint32
+	}{
+		{SocketQuery{Protocal: ProtocalTCP, Af: unix.AF_INET}, 4242},
+		{SocketQuery{Protocal: ProtocalUnix, Af: unix.AF_UNIX}, 102},
+	} {
+		var inodes []uint32
+		err := c.ForEachSocket(tt.q, func(si *SocketInfo) bool {
+			inodes = append(inodes, si.Inode)
+			return true
+		})
+		if err != nil {
+			t.Errorf("protocal %d: %v", tt.q.Protocal, err)
+		}
+		if len(inodes) != 1 || inodes[0] != tt.inode {
+			t.Errorf("protocal %d: got inodes %v, want only %d", tt.q.Protocal, inodes, tt.inode)
+		}
+	}
+}
+
+// requestTransport records the requests it gets and acks each of them.
+type requestTransport struct {
+	requests [][]byte
+}
+
+func (t *requestTransport) Send(request []byte) error {
+	t.requests = append(t.requests, append([]byte(nil), request...))
+	return nil
+}
+
+func (t *requestTransport) Recv() ([]byte, error) {
+	return netlinkMessage(unix.NLMSG_ERROR, make([]byte, unix.SizeofNlMsgerr)), nil
+}
+
+func (t *requestTransport) Reset() error { return nil }
+func (t *requestTransport) Close() error { return nil }
+
+func TestDestroyCookie(t *testing.T) {
+	for _, tt := range []struct {
+		source int
+		want   uint64
+	}{
+		{SourceNetlink, 0x1234},
+		{SourceProc, INET_DIAG_NOCOOKIE},
+	} {
+		transport := new(requestTransport)
+		c := NewClient()
+		c.Transport = transport
+		si := NewSocketInfo()
+		si.Protocal = ProtocalTCP
+		si.Source = tt.source
+		si.SK = 0x1234
+		si.LocalAddr, _ = parseProcAddrPort("0100007F:1F90")
+		si.RemoteAddr, _ = parseProcAddrPort("0100007F:9C40")
+		if err := c.Destroy(si); err != nil {
+			t.Fatalf("source %d: %v", tt.source, err)
+		}
+		req := (*InetDiagRequest)(unsafe.Pointer(&transport.requests[0][0]))
+		cookie := req.Request.ID.IdiagCookie
+		if got := uint64(cookie[1])<<32 | uint64(cookie[0]); got != tt.want {
+			t.Errorf("source %d: cookie %x, want %x", tt.source, got, tt.want)
+		}
+	}
+}
+
+// oldKernelTransport answers like a kernel that does not know
+// INET_DIAG_REQ_PROTOCOL: the dump goes by sdiag_protocol alone.
+type oldKernelTransport struct {
+	stubTransport
+	refused bool
+}
+
+func (t *oldKernelTransport) Send(request []byte) error {
+	t.pos = 0
+	req := (*InetDiagRequest)(unsafe.Pointer(&request[0]))
+	if req.Request.SdiagProtocol != unix.IPPROTO_TCP {
+		t.refused = true
+		return unix.ENOENT
+	}
+	return nil
+}
+
+func TestForEachSocketMPTCPOldKernel(t *testing.T) {
+	var msg InetDiagMessage
+	msg.IdiagFamily = unix.AF_INET
+	msg.IdiagState = SsLISTEN
+	msg.IdiagInode = 7
+	var datagram []byte
+	datagram = append(datagram, netlinkMessage(SOCK_DIAG_BY_FAMILY, (*[SizeOfInetDiagMsg]byte)(unsafe.Pointer(&msg))[:])...)
+	datagram = append(datagram, netlinkMessage(unix.NLMSG_DONE, make([]byte, 4))...)
+
+	transport := &oldKernelTransport{stubTransport: stubTransport{answer: [][]byte{datagram}}}
+	c := newProcClient(t, nil)
+	c.Transport = transport
+	called := false
+	err := c.ForEachSocket(SocketQuery{Protocal: ProtocalMPTCP, Af: unix.AF_INET}, func(si *SocketInfo) bool {
+		called = true
+		return true
+	})
+	if !transport.refused {
+		t.Errorf("the MPTCP request went out as a TCP dump")
+	}
+	if !errors.Is(err, ErrUnsupportedFamily) {
+		t.Errorf("got error %v, want ErrUnsupportedFamily", err)
+	}
+	if called {
+		t.Errorf("got the TCP sockets labelled as MPTCP")
+	}
+}
@@ -0,0 +1,59 @@
+// +build linux
+
+package psss
+
+import (
+	"strconv"
+	"strings"
+)
+
+// DCCP reuses the TCP state numbers, except for PARTOPEN and PASSIVE_CLOSEREQ
+// which follow TCP_MAX_STATES and so move whenever TCP gains a state.
+func dccpPartOpenState() uint8 {
+	major, minor := kernelRelease()
+	switch {
+	case major < 4 || major == 4 && minor < 4:
+		return 12
+	case major < 6 || major == 6 && minor < 6:
+		// after TCP_NEW_SYN_RECV
+		return 13
+	default:
+		// after TCP_BOUND_INACTIVE
+		return 14
+	}
+}
+
+func kernelRelease() (major, minor int) {
+	fields := strings.SplitN(KVer.UTSRelease, ".", 3)
+	if len(fields) < 2 {
+		return 0, 0
+	}
+	major, _ = strconv.Atoi(fields[0])
+	minor, _ = strconv.Atoi(strings.TrimRightFunc(fields[1], func(r rune) bool { return r < '0' || r > '9' }))
+	return major, minor
+}
+
+// dccpStates adds the DCCP only states to a generic state mask.
+func dccpStates(states uint32) uint32 {
+	partOpen := dccpPartOpenState()
+	if states&(1<<SsESTAB) != 0 {
+		states |= 1 << partOpen
+	}
+	if states&(1<<SsCLOSEWAIT) != 0 {
+		states |= 1 << (partOpen + 1)
+	}
+	return states
+}
+
+func (si *SocketInfo) fixDCCPState() {
+	switch partOpen := dccpPartOpenState(); si.Status {
+	case partOpen:
+		si.Status = SsESTAB
+	case partOpen + 1:
+		si.Status = SsCLOSEWAIT
+	default:
+		if si.Status >= SsMAX {
+			si.Status = SsUNKNOWN
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// +build linux
+
+package psss
+
+import (
+	"fmt"
+	"net/netip"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// INET_DIAG_NOCOOKIE tells sock_diag to find a socket by its addresses alone
+const INET_DIAG_NOCOOKIE = ^uint64(0)
+
+// NewInetDiagSockID builds the id sock_diag knows a socket by, the cookie
+// makes sure a reused address pair does not hit a newer socket.
+func NewInetDiagSockID(local, remote SockAddr, cookie uint64) (family uint8, id InetDiagSockID) {
+	family = unix.AF_INET
+	if !local.Addr().Is4() {
+		family = unix.AF_INET6
+	}
+	id.IdiagSport = ntohs(local.Port())
+	id.IdiagDport = ntohs(remote.Port())
+	id.IdiagSrc = diagRawAddr(local.Addr(), family)
+	id.IdiagDst = diagRawAddr(remote.Addr(), family)
+	id.IdiagIF = local.IfIndex
+	id.IdiagCookie = [2]uint32{uint32(cookie), uint32(cookie >> 32)}
+	return family, id
+}
+
+// diagRawAddr is the reverse of diagAddr.
+func diagRawAddr(addr netip.Addr, family uint8) (raw [4]uint32) {
+	b := (*[16]byte)(unsafe.Pointer(&raw))
+	if family == unix.AF_INET {
+		if addr.Is4() || addr.Is4In6() {
+			a4 := addr.Unmap().As4()
+			copy(b[:], a4[:])
+		}
+		return raw
+	}
+	*b = addr.As16()
+	return raw
+}
+
+// DestroySocket asks the kernel to close a TCP or UDP socket with
+// SOCK_DESTROY. It needs CAP_NET_ADMIN, otherwise the error matches
+// ErrPermission, and it matches ErrUnsupportedFamily when the kernel was built
+// without CONFIG_INET_DIAG_DESTROY.
+func (c *Client) DestroySocket(family uint8, protocal int, id InetDiagSockID) error {
+	if protocal != ProtocalTCP && protocal != ProtocalUDP {
+		return &NetlinkError{Errno: unix.EOPNOTSUPP}
+	}
+	ipproto, err := inetProtocol(protocal)
+	if err != nil {
+		return err
+	}
+	var req InetDiagRequest
+	req.Header.Len = SizeOfInetDiagRequest
+	req.Header.Type = SOCK_DESTROY
+	req.Header.Flags = unix.NLM_F_REQUEST | unix.NLM_F_ACK
+	req.Request.SdiagFamily = family
+	req.Request.SdiagProtocol = uint8(ipproto)
+	req.Request.IdiagStates = SsAllStates
+	req.Request.ID = id
+	request := make([]byte, SizeOfInetDiagRequest)
+	*(*InetDiagRequest)(unsafe.Pointer(&request[0])) = req
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.ack(request)
+}
+
+// Destroy closes a socket returned by a dump of the same kind, it must not be
+// called from inside ForEachSocket of the same Client. Sockets read from the
+// proc files have no cookie, they are found by their addresses.
+func (c *Client) Destroy(si *SocketInfo) error {
+	cookie := si.SK
+	if si.Source != SourceNetlink {
+		// SK holds the kernel address of the socket there
+		cookie = INET_DIAG_NOCOOKIE
+	}
+	family, id := NewInetDiagSockID(si.LocalAddr, si.RemoteAddr, cookie)
+	return c.DestroySocket(family, si.Protocal, id)
+}
+
+// ack sends a request that is answered with a single NLMSG_ERROR.
+func (c *Client) ack(request []byte) (err error) {
+	t := c.transport()
+	if err = t.Send(request); err != nil {
+		return wrapErrno(err)
+	}
+	data, err := t.Recv()
+	if err != nil {
+		t.Reset()
+		return wrapErrno(err)
+	}
+	raw, err := syscall.ParseNetlinkMessage(data)
+	if err != nil {
+		t.Reset()
+		return fmt.Errorf("parse netlink message error:[%v]", err)
+	}
+	if len(raw) == 0 || raw[0].Header.Type != unix.NLMSG_ERROR {
+		t.Reset()
+		return fmt.Errorf("unexpected netlink answer")
+	}
+	return netlinkError(&raw[0])
+}
@@ -0,0 +1,120 @@
+// +build linux
+
+package psss
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// netlink extended ACK attributes
+const (
+	NLMSGERR_ATTR_UNUSED = iota
+	NLMSGERR_ATTR_MSG
+	NLMSGERR_ATTR_OFFS
+	NLMSGERR_ATTR_COOKIE
+)
+
+// dumpRetries bounds how often an interrupted dump is started over
+const dumpRetries = 3
+
+// NetlinkError is an error answered by the kernel, or met on the netlink
+// socket itself. Msg is the extended ACK message, when the kernel gave one.
+// It matches ErrPermission and ErrUnsupportedFamily with errors.Is.
+type NetlinkError struct {
+	Errno syscall.Errno
+	Msg   string
+}
+
+func (e *NetlinkError) Error() string {
+	if len(e.Msg) > 0 {
+		return fmt.Sprintf("%v:[%s]", e.Errno, e.Msg)
+	}
+	return e.Errno.Error()
+}
+
+func (e *NetlinkError) Unwrap() error {
+	return e.Errno
+}
+
+func (e *NetlinkError) Is(target error) bool {
+	switch e.Errno {
+	case unix.EPERM, unix.EACCES:
+		return target == ErrPermission
+	case unix.ENOENT, unix.EAFNOSUPPORT, unix.EPROTONOSUPPORT, unix.EOPNOTSUPP:
+		// sock_diag answers ENOENT when there is no handler for the request
+		return target == ErrUnsupportedFamily
+	}
+	return false
+}
+
+// wrapErrno turns a bare errno into a *NetlinkError.
+func wrapErrno(err error) error {
+	if errno, ok := err.(syscall.Errno); ok {
+		return &NetlinkError{Errno: errno}
+	}
+	return err
+}
+
+// netlinkError decodes the payload of NLMSG_ERROR, or of the NLMSG_DONE ending
+// a failed dump, into nil or a *NetlinkError.
+func netlinkError(m *syscall.NetlinkMessage) error {
+	if len(m.Data) < 4 {
+		return fmt.Errorf("truncated netlink error")
+	}
+	errno := -*(*int32)(unsafe.Pointer(&m.Data[0]))
+	if errno == 0 {
+		return nil
+	}
+	e := &NetlinkError{Errno: syscall.Errno(errno)}
+	if m.Header.Flags&unix.NLM_F_ACK_TLVS == 0 {
+		return e
+	}
+	// the attributes follow the request, which is echoed unless capped
+	cursor := 4
+	if m.Header.Type == unix.NLMSG_ERROR {
+		if len(m.Data) < unix.SizeofNlMsgerr {
+			return e
+		}
+		cursor = unix.SizeofNlMsgerr
+		if m.Header.Flags&unix.NLM_F_CAPPED == 0 {
+			cursor = 4 + int((*unix.NlMsghdr)(unsafe.Pointer(&m.Data[4])).Len)
+		}
+	}
+	var nlAttr unix.NlAttr
+	for cursor = rtaAlign(cursor); cursor+unix.SizeofNlAttr <= len(m.Data); cursor += rtaAlign(int(nlAttr.Len)) {
+		nlAttr = *(*unix.NlAttr)(unsafe.Pointer(&m.Data[cursor]))
+		if nlAttr.Len < unix.SizeofNlAttr || cursor+int(nlAttr.Len) > len(m.Data) {
+			break
+		}
+		if nlAttr.Type == NLMSGERR_ATTR_MSG {
+			e.Msg = strings.TrimRight(string(m.Data[cursor+unix.SizeofNlAttr:cursor+int(nlAttr.Len)]), "\x00")
+			break
+		}
+	}
+	return e
+}
+
+// canReadProc tells whether the proc files may still answer after sock_diag
+// failed with err: the kernel lacks the diag module for the family or
+// protocol, or the netlink socket was refused.
+func canReadProc(err error) bool {
+	if ne, ok := err.(*NetlinkError); ok {
+		return ne.Is(ErrUnsupportedFamily) || ne.Is(ErrPermission)
+	}
+	return false
+}
+
+// procError picks the error to report when the proc fallback failed too. A
+// missing proc file says no more than the netlink error did.
+func procError(netlinkErr, procErr error) error {
+	if os.IsNotExist(procErr) {
+		return netlinkErr
+	}
+	return procErr
+}
@@ -0,0 +1,410 @@
+package psss
+
+import (
+	"fmt"
+	"net/netip"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Filter expression grammar, same as iproute2 ss:
+//
+//	[ state STATE-FILTER ] [ EXPRESSION ]
+//	EXPRESSION := EXPRESSION [ and | or ] EXPRESSION | not EXPRESSION | ( EXPRESSION ) |
+//	              src HOSTCOND | dst HOSTCOND | sport OP PORT | dport OP PORT
+//	OP := = | == | eq | != | ne | >= | ge | <= | le | > | gt | < | lt
+//	HOSTCOND := ADDR[/PREFIXLEN][:PORT] | [ADDR6][/PREFIXLEN][:PORT] | *[:PORT] | :PORT |
+//	            /PATH-GLOB | @ABSTRACT-GLOB
+
+const (
+	FilterAND = iota
+	FilterOR
+	FilterNOT
+	FilterSrcCond
+	FilterDstCond
+	FilterSrcPortGE
+	FilterSrcPortLE
+	FilterSrcPortEQ
+	FilterDstPortGE
+	FilterDstPortLE
+	FilterDstPortEQ
+)
+
+var (
+	StateFilterName = map[string]uint32{
+		"established": 1 << SsESTAB,
+		"syn-sent":    1 << SsSYNSENT,
+		"syn-recv":    1 << SsSYNRECV,
+		"fin-wait-1":  1 << SsFINWAIT1,
+		"fin-wait-2":  1 << SsFINWAIT2,
+		"time-wait":   1 << SsTIMEWAIT,
+		"closed":      1 << SsUNCONN,
+		"unconnected": 1 << SsUNCONN,
+		"close-wait":  1 << SsCLOSEWAIT,
+		"last-ack":    1 << SsLASTACK,
+		"listening":   1 << SsLISTEN,
+		"listen":      1 << SsLISTEN,
+		"closing":     1 << SsCLOSING,
+
+		"all":          SsAllStates,
+		"connected":    SsAllStates &^ (1<<SsLISTEN | 1<<SsUNCONN),
+		"synchronized": SsAllStates &^ (1<<SsLISTEN | 1<<SsUNCONN | 1<<SsSYNSENT),
+		"bucket":       1<<SsSYNRECV | 1<<SsTIMEWAIT,
+		"big":          SsAllStates &^ (1<<SsSYNRECV | 1<<SsTIMEWAIT),
+	}
+)
+
+const SsAllStates uint32 = (1 << SsMAX) - 1
+
+type HostCond struct {
+	Family uint8 // AF_UNSPEC matches any address
+	Prefix netip.Prefix
+	Port   int    // -1 matches any port
+	Path   string // glob on the escaped unix name, only for AF_UNIX
+}
+
+type FilterNode struct {
+	Type  int
+	Left  *FilterNode
+	Right *FilterNode // only for FilterAND and FilterOR
+	Cond  HostCond    // only for host and port conditions
+}
+
+type SocketFilter struct {
+	States uint32 // zero if no state filter was given
+	Expr   *FilterNode
+}
+
+type filterParser struct {
+	tokens []string
+	cursor int
+}
+
+func ParseSocketFilter(args []string) (f *SocketFilter, err error) {
+	f = new(SocketFilter)
+	p := &filterParser{tokens: tokenizeFilter(strings.Join(args, " "))}
+	for p.peek() == "state" || p.peek() == "exclude" || p.peek() == "excl" {
+		keyword := p.next()
+		name := p.next()
+		mask, ok := StateFilterName[name]
+		if !ok {
+			return nil, fmt.Errorf("invalid state:[%s]", name)
+		}
+		if keyword == "state" {
+			f.States |= mask
+			continue
+		}
+		if f.States == 0 {
+			f.States = SsAllStates
+		}
+		f.States &^= mask
+	}
+	if p.peek() == "" {
+		return f, nil
+	}
+	if f.Expr, err = p.parseOr(); err != nil {
+		return nil, err
+	}
+	if p.peek() != "" {
+		return nil, fmt.Errorf("unexpected token:[%s]", p.peek())
+	}
+	return f, nil
+}
+
+func tokenizeFilter(raw string) (tokens []string) {
+	var token []byte
+	flush := func() {
+		if len(token) > 0 {
+			tokens = append(tokens, string(token))
+			token = token[:0]
+		}
+	}
+	for i := 0; i < len(raw); i++ {
+		switch c := raw[i]; c {
+		case ' ', '\t', '\n':
+			flush()
+		case '(', ')':
+			flush()
+			tokens = append(tokens, string(c))
+		case '!', '=', '<', '>', '&', '|':
+			// operator characters never appear in addresses
+			flush()
+			token = append(token, c)
+			for i+1 < len(raw) && strings.IndexByte("=&|", raw[i+1]) >= 0 {
+				i++
+				token = append(token, raw[i])
+			}
+			flush()
+		default:
+			token = append(token, c)
+		}
+	}
+	flush()
+	return tokens
+}
+
+func (p *filterParser) peek() string {
+	if p.cursor >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.cursor]
+}
+
+func (p *filterParser) next() string {
+	t := p.peek()
+	if t != "" {
+		p.cursor++
+	}
+	return t
+}
+
+func (p *filterParser) parseOr() (n *FilterNode, err error) {
+	if n, err = p.parseAnd(); err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek() {
+		case "or", "|", "||":
+			p.next()
+		default:
+			return n, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		n = &FilterNode{Type: FilterOR, Left: n, Right: right}
+	}
+}
+
+func (p *filterParser) parseAnd() (n *FilterNode, err error) {
+	if n, err = p.parseUnary(); err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek() {
+		case "and", "&", "&&":
+			p.next()
+		case "", ")", "or", "|", "||":
+			return n, nil
+		}
+		// juxtaposition means and
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		n = &FilterNode{Type: FilterAND, Left: n, Right: right}
+	}
+}
+
+func (p *filterParser) parseUnary() (n *FilterNode, err error) {
+	switch t := p.next(); t {
+	case "not", "!":
+		if n, err = p.parseUnary(); err != nil {
+			return nil, err
+		}
+		return &FilterNode{Type: FilterNOT, Left: n}, nil
+	case "(":
+		if n, err = p.parseOr(); err != nil {
+			return nil, err
+		}
+		if t = p.next(); t != ")" {
+			return nil, fmt.Errorf("missing ')' before:[%s]", t)
+		}
+		return n, nil
+	case "src", "dst":
+		return p.parseHost(t)
+	case "sport", "dport":
+		return p.parsePort(t)
+	case "":
+		return nil, fmt.Errorf("unexpected end of filter")
+	default:
+		return nil, fmt.Errorf("unexpected token:[%s]", t)
+	}
+}
+
+func (p *filterParser) parseHost(keyword string) (n *FilterNode, err error) {
+	negate := false
+	switch p.peek() {
+	case "=", "==", "eq":
+		p.next()
+	case "!=", "ne":
+		p.next()
+		negate = true
+	}
+	n = &FilterNode{Type: FilterSrcCond}
+	if keyword == "dst" {
+		n.Type = FilterDstCond
+	}
+	if n.Cond, err = ParseHostCond(p.next()); err != nil {
+		return nil, err
+	}
+	if negate {
+		n = &FilterNode{Type: FilterNOT, Left: n}
+	}
+	return n, nil
+}
+
+func (p *filterParser) parsePort(keyword string) (n *FilterNode, err error) {
+	op := "="
+	switch p.peek() {
+	case "=", "==", "eq", "!=", "ne", ">=", "ge", "<=", "le", ">", "gt", "<", "lt":
+		op = p.next()
+	}
+	raw := p.next()
+	port, err := strconv.ParseUint(strings.TrimPrefix(raw, ":"), 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port:[%s]", raw)
+	}
+	ge, le, eq := FilterSrcPortGE, FilterSrcPortLE, FilterSrcPortEQ
+	if keyword == "dport" {
+		ge, le, eq = FilterDstPortGE, FilterDstPortLE, FilterDstPortEQ
+	}
+	cond := HostCond{Port: int(port)}
+	switch op {
+	case "=", "==", "eq":
+		return &FilterNode{Type: eq, Cond: cond}, nil
+	case "!=", "ne":
+		return &FilterNode{Type: FilterNOT, Left: &FilterNode{Type: eq, Cond: cond}}, nil
+	case ">=", "ge":
+		return &FilterNode{Type: ge, Cond: cond}, nil
+	case "<=", "le":
+		return &FilterNode{Type: le, Cond: cond}, nil
+	case ">", "gt":
+		return &FilterNode{Type: FilterNOT, Left: &FilterNode{Type: le, Cond: cond}}, nil
+	default:
+		return &FilterNode{Type: FilterNOT, Left: &FilterNode{Type: ge, Cond: cond}}, nil
+	}
+}
+
+func ParseHostCond(raw string) (hc HostCond, err error) {
+	hc.Port = -1
+	if raw == "" {
+		return hc, fmt.Errorf("missing host condition")
+	}
+	if raw[0] == '/' || raw[0] == '@' {
+		if _, err = path.Match(raw, ""); err != nil {
+			return hc, fmt.Errorf("invalid path pattern:[%s]", raw)
+		}
+		hc.Family = syscall.AF_UNIX
+		hc.Path = raw
+		return hc, nil
+	}
+	host := raw
+	switch {
+	case strings.HasPrefix(raw, "["):
+		end := strings.IndexByte(raw, ']')
+		if end < 0 {
+			return hc, fmt.Errorf("invalid host:[%s]", raw)
+		}
+		host = raw[1:end]
+		rest := raw[end+1:]
+		if i := strings.IndexByte(rest, ':'); i >= 0 {
+			if hc.Port, err = parseCondPort(rest[i+1:]); err != nil {
+				return hc, err
+			}
+			rest = rest[:i]
+		}
+		host += rest
+	case strings.Count(raw, ":") == 1:
+		i := strings.IndexByte(raw, ':')
+		if hc.Port, err = parseCondPort(raw[i+1:]); err != nil {
+			return hc, err
+		}
+		host = raw[:i]
+	}
+	if host == "" || host == "*" {
+		hc.Family = syscall.AF_UNSPEC
+		return hc, nil
+	}
+	prefix := ""
+	if i := strings.IndexByte(host, '/'); i >= 0 {
+		host, prefix = host[:i], host[i+1:]
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return hc, fmt.Errorf("invalid address:[%s]", host)
+	}
+	hc.Family = syscall.AF_INET6
+	if addr.Is4() {
+		hc.Family = syscall.AF_INET
+	}
+	bits := addr.BitLen()
+	if prefix != "" {
+		if bits, err = strconv.Atoi(prefix); err != nil {
+			return hc, fmt.Errorf("invalid prefix length:[%s]", prefix)
+		}
+	}
+	if hc.Prefix, err = addr.Prefix(bits); err != nil {
+		return hc, fmt.Errorf("invalid prefix length:[%s]", prefix)
+	}
+	return hc, nil
+}
+
+func parseCondPort(raw string) (int, error) {
+	if raw == "" || raw == "*" {
+		return -1, nil
+	}
+	port, err := strconv.ParseUint(raw, 10, 16)
+	if err != nil {
+		return -1, fmt.Errorf("invalid port:[%s]", raw)
+	}
+	return int(port), nil
+}
+
+func (hc *HostCond) Match(sa SockAddr) bool {
+	if hc.Family == syscall.AF_UNIX {
+		return !sa.IsInet() && globMatch(hc.Path, sa.Name)
+	}
+	if hc.Port != -1 && hc.Port != int(sa.Port()) {
+		return false
+	}
+	if hc.Family == syscall.AF_UNSPEC {
+		return true
+	}
+	addr := sa.Addr()
+	if hc.Family == syscall.AF_INET {
+		// same as the kernel, IPv4 conditions also match v4-mapped addresses
+		addr = addr.Unmap()
+	}
+	return hc.Prefix.Contains(addr)
+}
+
+func (n *FilterNode) Match(si *SocketInfo) bool {
+	sport := int(si.LocalAddr.Port())
+	dport := int(si.RemoteAddr.Port())
+	switch n.Type {
+	case FilterAND:
+		return n.Left.Match(si) && n.Right.Match(si)
+	case FilterOR:
+		return n.Left.Match(si) || n.Right.Match(si)
+	case FilterNOT:
+		return !n.Left.Match(si)
+	case FilterSrcCond:
+		return n.Cond.Match(si.LocalAddr)
+	case FilterDstCond:
+		return n.Cond.Match(si.RemoteAddr)
+	case FilterSrcPortGE:
+		return sport >= n.Cond.Port
+	case FilterSrcPortLE:
+		return sport <= n.Cond.Port
+	case FilterSrcPortEQ:
+		return sport == n.Cond.Port
+	case FilterDstPortGE:
+		return dport >= n.Cond.Port
+	case FilterDstPortLE:
+		return dport <= n.Cond.Port
+	case FilterDstPortEQ:
+		return dport == n.Cond.Port
+	}
+	return false
+}
+
+func (f *SocketFilter) Match(si *SocketInfo) bool {
+	if f.States != 0 && f.States&(1<<si.Status) == 0 {
+		return false
+	}
+	return f.Expr == nil || f.Expr.Match(si)
+}
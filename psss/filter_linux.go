@@ -0,0 +1,150 @@
+// +build linux
+
+package psss
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	INET_DIAG_REQ_NONE = iota
+	INET_DIAG_REQ_BYTECODE
+	INET_DIAG_REQ_SK_BPF_STORAGES
+	INET_DIAG_REQ_PROTOCOL
+)
+
+const (
+	INET_DIAG_BC_NOP = iota
+	INET_DIAG_BC_JMP
+	INET_DIAG_BC_S_GE
+	INET_DIAG_BC_S_LE
+	INET_DIAG_BC_D_GE
+	INET_DIAG_BC_D_LE
+	INET_DIAG_BC_AUTO
+	INET_DIAG_BC_S_COND
+	INET_DIAG_BC_D_COND
+)
+
+const (
+	SizeOfInetDiagBcOp     = 4
+	SizeOfInetDiagHostcond = 8
+)
+
+type InetDiagBcOp struct {
+	Code uint8
+	Yes  uint8
+	No   uint16
+}
+
+type InetDiagHostcond struct {
+	Family    uint8
+	PrefixLen uint8
+	Pad       uint16
+	Port      int32
+}
+
+// Every compiled block follows the kernel convention: on success jump "yes"
+// bytes to the next block, on failure jump "no" bytes, where jumping exactly
+// to the end of the program accepts the socket and 4 bytes past it rejects.
+func (n *FilterNode) Bytecode() []byte {
+	switch n.Type {
+	case FilterAND:
+		a := n.Left.Bytecode()
+		b := n.Right.Bytecode()
+		bcPatch(a, len(b))
+		return append(a, b...)
+	case FilterOR:
+		a := n.Left.Bytecode()
+		b := n.Right.Bytecode()
+		code := make([]byte, len(a)+SizeOfInetDiagBcOp, len(a)+SizeOfInetDiagBcOp+len(b))
+		copy(code, a)
+		putBcOp(code[len(a):], INET_DIAG_BC_JMP, SizeOfInetDiagBcOp, uint16(len(b)+SizeOfInetDiagBcOp))
+		return append(code, b...)
+	case FilterNOT:
+		a := n.Left.Bytecode()
+		code := make([]byte, len(a)+SizeOfInetDiagBcOp)
+		copy(code, a)
+		putBcOp(code[len(a):], INET_DIAG_BC_JMP, SizeOfInetDiagBcOp, 2*SizeOfInetDiagBcOp)
+		return code
+	case FilterSrcCond:
+		return hostcondBytecode(INET_DIAG_BC_S_COND, &n.Cond)
+	case FilterDstCond:
+		return hostcondBytecode(INET_DIAG_BC_D_COND, &n.Cond)
+	case FilterSrcPortGE:
+		return portBytecode(INET_DIAG_BC_S_GE, n.Cond.Port)
+	case FilterSrcPortLE:
+		return portBytecode(INET_DIAG_BC_S_LE, n.Cond.Port)
+	case FilterDstPortGE:
+		return portBytecode(INET_DIAG_BC_D_GE, n.Cond.Port)
+	case FilterDstPortLE:
+		return portBytecode(INET_DIAG_BC_D_LE, n.Cond.Port)
+	case FilterSrcPortEQ:
+		// S_EQ and D_EQ only exist on recent kernels, GE plus LE works everywhere
+		a := portBytecode(INET_DIAG_BC_S_GE, n.Cond.Port)
+		b := portBytecode(INET_DIAG_BC_S_LE, n.Cond.Port)
+		bcPatch(a, len(b))
+		return append(a, b...)
+	case FilterDstPortEQ:
+		a := portBytecode(INET_DIAG_BC_D_GE, n.Cond.Port)
+		b := portBytecode(INET_DIAG_BC_D_LE, n.Cond.Port)
+		bcPatch(a, len(b))
+		return append(a, b...)
+	}
+	return nil
+}
+
+func putBcOp(b []byte, code, yes uint8, no uint16) {
+	*(*InetDiagBcOp)(unsafe.Pointer(&b[0])) = InetDiagBcOp{Code: code, Yes: yes, No: no}
+}
+
+// bcPatch redirects the rejecting jumps of code so that they skip reloc more
+// bytes, which is needed when another block is appended after it.
+func bcPatch(code []byte, reloc int) {
+	var op *InetDiagBcOp
+	for cursor := 0; cursor < len(code); cursor += int(op.Yes) {
+		op = (*InetDiagBcOp)(unsafe.Pointer(&code[cursor]))
+		if int(op.No) == len(code)-cursor+SizeOfInetDiagBcOp {
+			op.No += uint16(reloc)
+		}
+	}
+}
+
+func portBytecode(code uint8, port int) []byte {
+	b := make([]byte, 2*SizeOfInetDiagBcOp)
+	putBcOp(b, code, 2*SizeOfInetDiagBcOp, 3*SizeOfInetDiagBcOp)
+	putBcOp(b[SizeOfInetDiagBcOp:], INET_DIAG_BC_NOP, 0, uint16(port))
+	return b
+}
+
+func hostcondBytecode(code uint8, hc *HostCond) []byte {
+	var addr []byte
+	if hc.Family == unix.AF_UNIX {
+		// unix paths never match an inet socket, the kernel would refuse the
+		// family anyway
+		b := make([]byte, SizeOfInetDiagBcOp)
+		putBcOp(b, INET_DIAG_BC_JMP, SizeOfInetDiagBcOp, 2*SizeOfInetDiagBcOp)
+		return b
+	}
+	cond := InetDiagHostcond{Family: hc.Family, Port: int32(hc.Port)}
+	if hc.Family != unix.AF_UNSPEC {
+		// the kernel refuses a prefix longer than the address, so an any
+		// address keeps 0
+		addr = hc.Prefix.Addr().AsSlice()
+		cond.PrefixLen = uint8(hc.Prefix.Bits())
+	}
+	length := SizeOfInetDiagBcOp + SizeOfInetDiagHostcond + len(addr)
+	b := make([]byte, length)
+	putBcOp(b, code, uint8(length), uint16(length+SizeOfInetDiagBcOp))
+	*(*InetDiagHostcond)(unsafe.Pointer(&b[SizeOfInetDiagBcOp])) = cond
+	copy(b[SizeOfInetDiagBcOp+SizeOfInetDiagHostcond:], addr)
+	return b
+}
+
+func (f *SocketFilter) Bytecode() []byte {
+	if f == nil || f.Expr == nil {
+		return nil
+	}
+	return f.Expr.Bytecode()
+}
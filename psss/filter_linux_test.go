@@ -0,0 +1,88 @@
+// +build linux
+
+package psss
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestFilterBytecode(t *testing.T) {
+	// the wanted programs are those iproute2 6.1 ss sends for the same
+	// expressions, taken from its INET_DIAG_REQ_BYTECODE attribute
+	for _, tt := range []struct {
+		expr string
+		want string
+	}{
+		{"sport >= :1024", "02080c00 00000004"},
+		{"sport >= :1024 and sport <= :2048", "02081400 00000004 03080c00 00000008"},
+		{"dport < :80 or dport > :1000", "04080c00 00005000 01040800 01041000 05080c00 0000e803 01040800"},
+		{"not sport >= :1024", "02080c00 00000004 01040800"},
+		{"src 127.0.0.0/8", "07101400 02080000 ffffffff 7f000000"},
+		{"src 127.0.0.0/8 and dport >= :80", "07101c00 02080000 ffffffff 7f000000 04080c00 00005000"},
+		{"( sport >= :1 or sport <= :2 ) and not dport >= :3",
+			"02080c00 00000100 01040c00 03081800 00000200 04080c00 00000300 01040800"},
+		{"dst [::1]:443", "081c2000 0a800000 bb010000 00000000 00000000 00000000 00000001"},
+		{"sport >= :1 or sport >= :2 or sport >= :3",
+			"02080c00 00000100 01040c00 02080c00 00000200 01040c00 02080c00 00000300"},
+		{"not ( src 10.0.0.0/8 or src 192.168.0.0/16 )",
+			"07101400 02080000 ffffffff 0a000000 01041400 07101400 02100000 ffffffff c0a80000 01040800"},
+		// iproute2 sends S_COND for these, GE plus LE is the same on every kernel
+		{"sport = :22", "02081400 00001600 03080c00 00001600"},
+		{"dport != :22", "04081400 00001600 05080c00 00001600 01040800"},
+		// iproute2 pads the missing address with 4 zero bytes, the kernel reads
+		// none for AF_UNSPEC
+		{"src *:22", "070c1000 00000000 16000000"},
+		{"dst *", "080c1000 00000000 ffffffff"},
+		// unix paths never match an inet socket
+		{"src /run/*", "01040800"},
+		{"src /run/* or sport >= :1", "01040800 01040c00 02080c00 00000100"},
+	} {
+		f, err := ParseSocketFilter(strings.Fields(tt.expr))
+		if err != nil {
+			t.Errorf("%q: %v", tt.expr, err)
+			continue
+		}
+		if got := hex.EncodeToString(f.Bytecode()); got != hexString(tt.want) {
+			t.Errorf("%q: got %s, want %s", tt.expr, got, tt.want)
+		}
+	}
+
+	var f *SocketFilter
+	if f.Bytecode() != nil {
+		t.Errorf("a nil filter compiled to bytecode")
+	}
+	if f, _ = ParseSocketFilter([]string{"state", "listening"}); f.Bytecode() != nil {
+		t.Errorf("a state filter compiled to bytecode")
+	}
+}
+
+// hexString drops the spaces that group the bytes of an instruction
+func hexString(s string) string {
+	return strings.Replace(s, " ", "", -1)
+}
+
+func TestBcPatch(t *testing.T) {
+	for _, tt := range []struct {
+		code  string
+		reloc int
+		want  string
+	}{
+		// sport >= :1 and sport >= :2, both reject 4 bytes past the end
+		{"02081400 00000100 02080c00 00000200", 8, "02081c00 00000100 02081400 00000200"},
+		// not sport >= :1, the condition accepts at the end and stays
+		{"02080c00 00000100 01040800", 8, "02080c00 00000100 01041000"},
+		// sport >= :1 or sport >= :2, only the last condition rejects
+		{"02080c00 00000100 01040c00 02080c00 00000200", 4, "02080c00 00000100 01040c00 02081000 00000200"},
+	} {
+		code, err := hex.DecodeString(hexString(tt.code))
+		if err != nil {
+			t.Fatal(err)
+		}
+		bcPatch(code, tt.reloc)
+		if got := hex.EncodeToString(code); got != hexString(tt.want) {
+			t.Errorf("%s: got %s, want %s", tt.code, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package psss
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+var filterTypeName = map[int]string{
+	FilterAND:       "and",
+	FilterOR:        "or",
+	FilterNOT:       "not",
+	FilterSrcCond:   "src",
+	FilterDstCond:   "dst",
+	FilterSrcPortGE: "sport>=",
+	FilterSrcPortLE: "sport<=",
+	FilterSrcPortEQ: "sport=",
+	FilterDstPortGE: "dport>=",
+	FilterDstPortLE: "dport<=",
+	FilterDstPortEQ: "dport=",
+}
+
+// filterString prints n in prefix form, e.g. and(sport>=1024,not(src 10.0.0.0/8))
+func filterString(n *FilterNode) string {
+	name := filterTypeName[n.Type]
+	switch n.Type {
+	case FilterAND, FilterOR:
+		return fmt.Sprintf("%s(%s,%s)", name, filterString(n.Left), filterString(n.Right))
+	case FilterNOT:
+		return fmt.Sprintf("%s(%s)", name, filterString(n.Left))
+	case FilterSrcCond, FilterDstCond:
+		if n.Cond.Path != "" {
+			return name + " " + n.Cond.Path
+		}
+		host := "*"
+		if n.Cond.Prefix.IsValid() {
+			host = n.Cond.Prefix.String()
+		}
+		return fmt.Sprintf("%s %s:%d", name, host, n.Cond.Port)
+	}
+	return fmt.Sprintf("%s%d", name, n.Cond.Port)
+}
+
+func TestParseSocketFilter(t *testing.T) {
+	for _, tt := range []struct {
+		expr string
+		want string
+	}{
+		{"sport = :22", "sport=22"},
+		{"sport == 22", "sport=22"},
+		{"dport != :80", "not(dport=80)"},
+		{"sport >= :1024", "sport>=1024"},
+		{"sport ge 1024", "sport>=1024"},
+		{"sport <= :1024", "sport<=1024"},
+		{"dport > :1000", "not(dport<=1000)"},
+		{"dport lt :80", "not(dport>=80)"},
+		{"sport>=:1024&&sport<=:2048", "and(sport>=1024,sport<=2048)"},
+		{"src 127.0.0.1", "src 127.0.0.1/32:-1"},
+		{"src 10.1.2.3/8:80", "src 10.0.0.0/8:80"},
+		{"dst [::1]:443", "dst ::1/128:443"},
+		{"dst [2001:db8::]/32", "dst 2001:db8::/32:-1"},
+		{"dst 2001:db8::1", "dst 2001:db8::1/128:-1"},
+		{"src *:22", "src *:22"},
+		{"src :22", "src *:22"},
+		{"src != 10.0.0.0/8", "not(src 10.0.0.0/8:-1)"},
+		{"src /run/*.sock", "src /run/*.sock"},
+		{"dst @abstract*", "dst @abstract*"},
+		// and binds tighter than or, both are left associative
+		{"sport = 1 or sport = 2 and sport = 3", "or(sport=1,and(sport=2,sport=3))"},
+		{"sport = 1 or sport = 2 or sport = 3", "or(or(sport=1,sport=2),sport=3)"},
+		{"sport = 1 sport = 2", "and(sport=1,sport=2)"},
+		{"( sport = 1 or sport = 2 ) and not dport = 3", "and(or(sport=1,sport=2),not(dport=3))"},
+		{"!(sport = 1 | sport = 2)", "not(or(sport=1,sport=2))"},
+		{"not not sport = 1", "not(not(sport=1))"},
+	} {
+		f, err := ParseSocketFilter(strings.Fields(tt.expr))
+		if err != nil {
+			t.Errorf("%q: %v", tt.expr, err)
+			continue
+		}
+		if f.States != 0 {
+			t.Errorf("%q: states %x, want none", tt.expr, f.States)
+		}
+		if got := filterString(f.Expr); got != tt.want {
+			t.Errorf("%q: got %s, want %s", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseSocketFilterStates(t *testing.T) {
+	for _, tt := range []struct {
+		expr string
+		want uint32
+	}{
+		{"", 0},
+		{"state established", 1 << SsESTAB},
+		{"state listening state time-wait", 1<<SsLISTEN | 1<<SsTIMEWAIT},
+		{"exclude listen", SsAllStates &^ (1 << SsLISTEN)},
+		{"state connected excl established", SsAllStates &^ (1<<SsLISTEN | 1<<SsUNCONN | 1<<SsESTAB)},
+	} {
+		f, err := ParseSocketFilter(strings.Fields(tt.expr))
+		if err != nil {
+			t.Errorf("%q: %v", tt.expr, err)
+			continue
+		}
+		if f.States != tt.want || f.Expr != nil {
+			t.Errorf("%q: states %x expr %v, want %x and no expression", tt.expr, f.States, f.Expr, tt.want)
+		}
+	}
+
+	f, err := ParseSocketFilter([]string{"state", "established", "dport", "=", ":443"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.States != 1<<SsESTAB || f.Expr == nil || filterString(f.Expr) != "dport=443" {
+		t.Errorf("got states %x expr %v, want established and dport=443", f.States, f.Expr)
+	}
+}
+
+func TestParseSocketFilterErrors(t *testing.T) {
+	for _, expr := range []string{
+		"state bogus",
+		"sport",
+		"sport = :70000",
+		"sport = http",
+		"src",
+		"src 1.2.3.4/33",
+		"src 1.2.3.4:99999",
+		"src 300.1.1.1",
+		"dst [::1",
+		"src /run/[",
+		"( sport = 1",
+		"sport = 1 )",
+		"sport = 1 or",
+		"not",
+		"bogus",
+	} {
+		if f, err := ParseSocketFilter(strings.Fields(expr)); err == nil {
+			t.Errorf("%q: got %s, want an error", expr, filterString(f.Expr))
+		}
+	}
+}
+
+func inetSockAddr(s string) SockAddr {
+	return SockAddr{AddrPort: netip.MustParseAddrPort(s)}
+}
+
+func TestFilterMatch(t *testing.T) {
+	si := NewSocketInfo()
+	si.Status = SsESTAB
+	si.LocalAddr = inetSockAddr("10.1.2.3:1500")
+	si.RemoteAddr = inetSockAddr("192.168.1.1:443")
+
+	si6 := NewSocketInfo()
+	si6.Status = SsLISTEN
+	si6.LocalAddr = inetSockAddr("[::ffff:10.1.2.3]:22")
+	si6.RemoteAddr = inetSockAddr("[::]:0")
+
+	siUnix := NewSocketInfo()
+	siUnix.Status = SsESTAB
+	siUnix.LocalAddr = SockAddr{Name: "/run/dbus/system_bus_socket"}
+
+	for _, tt := range []struct {
+		expr                string
+		inet, inet6, unixSk bool
+	}{
+		{"sport = :1500", true, false, false},
+		{"sport >= :1024 and sport <= :2048", true, false, false},
+		{"sport >= :1024 and sport <= :1499", false, false, false},
+		{"dport > :442", true, false, false},
+		{"dport < :443", false, true, true},
+		{"dport != :443", false, true, true},
+		{"src 10.0.0.0/8", true, true, false}, // the v4-mapped listener matches too
+		{"src [::ffff:10.0.0.0]/104", false, true, false},
+		{"src 10.0.0.0/8:22", false, true, false},
+		{"dst 192.168.0.0/16:443", true, false, false},
+		{"dst 192.168.0.0/24", false, false, false},
+		{"src *:1500", true, false, false},
+		{"not src 10.0.0.0/8", false, false, true},
+		{"sport = :22 or dport = :443", true, true, false},
+		{"( sport = :22 or dport = :443 ) and not src [::]/0", true, false, false},
+		{"src /run/*/*", false, false, true},
+		{"src /run/*", false, false, true}, // * crosses / as in iproute2
+		{"src @*", false, false, false},
+		{"state listening", false, true, false},
+		{"state established sport >= :1024", true, false, false},
+		{"exclude established", false, true, false},
+	} {
+		f, err := ParseSocketFilter(strings.Fields(tt.expr))
+		if err != nil {
+			t.Errorf("%q: %v", tt.expr, err)
+			continue
+		}
+		for _, c := range []struct {
+			si   *SocketInfo
+			want bool
+		}{
+			{si, tt.inet},
+			{si6, tt.inet6},
+			{siUnix, tt.unixSk},
+		} {
+			if got := f.Match(c.si); got != c.want {
+				t.Errorf("%q: Match(%s -> %s) = %v, want %v", tt.expr, c.si.LocalAddr, c.si.RemoteAddr, got, c.want)
+			}
+		}
+	}
+}
@@ -35,11 +35,6 @@ type Dirent struct {
 	IsEnd  bool
 }
 
-type Fd struct {
-	Dirent
-	Fresh bool
-}
-
 type DirentReader struct {
 	DataChan       chan Dirent
 	Buffer         []byte
@@ -194,7 +189,13 @@ func NewMountInfos() MountInfos {
 }
 
 func (mis *MountInfos) Get() error {
-	fd, err := os.Open(ProcRoot + "/self/mountinfo")
+	return mis.GetFrom(ProcRoot)
+}
+
+// GetFrom reads mountinfo of the proc mounted at procRoot, for the namespace
+// of ProcPid.
+func (mis *MountInfos) GetFrom(procRoot string) error {
+	fd, err := os.Open(filepath.Join(procRoot, ProcPid, "mountinfo"))
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,141 @@
+package psss
+
+import (
+	"fmt"
+)
+
+const (
+	TCPProblemRetransmitting = iota
+	TCPProblemRwndLimited
+	TCPProblemSndbufLimited
+	TCPProblemZeroWindow
+	TCPProblemRttVariance
+	TCPProblemAppLimited
+	TCPProblemCloseWait
+)
+
+var TCPProblemName = []string{
+	"retransmitting",
+	"rwnd_limited",
+	"sndbuf_limited",
+	"zero_window_probing",
+	"rtt_variance",
+	"app_limited",
+	"stuck_close_wait",
+}
+
+// timer of TimerState a socket uses to probe a zero window
+const timerPersist = 4
+
+// TCPFinding is a problem of a connection, Reason holds the numbers that
+// show it.
+type TCPFinding struct {
+	Problem int
+	Reason  string
+}
+
+func (f TCPFinding) String() string {
+	return TCPProblemName[f.Problem] + ": " + f.Reason
+}
+
+// TCPHealthLimits are the bounds DiagnoseTCP reports a problem beyond.
+type TCPHealthLimits struct {
+	RetransRatio float64 // retransmitted per sent segment
+	MinSegsOut   uint32  // fewer sent segments say nothing about the ratio
+	LimitedRatio float64 // time limited by rwnd or sndbuf per busy time
+	RttvarRatio  float64 // rttvar per smoothed rtt
+	MinRttvar    uint32  // usec, smaller variations do not matter
+	IdleAfter    uint32  // msec without sending after which a connection is idle
+}
+
+var DefaultTCPHealthLimits = TCPHealthLimits{
+	RetransRatio: 0.01,
+	MinSegsOut:   100,
+	LimitedRatio: 0.1,
+	RttvarRatio:  1,
+	MinRttvar:    5000,
+	IdleAfter:    1000,
+}
+
+// TCPHealth is a connection DiagnoseTCP found problems with.
+type TCPHealth struct {
+	Info     SocketInfo
+	Findings []TCPFinding
+}
+
+// Diagnose returns the problems of a single TCP socket. Everything but a
+// stuck CLOSE-WAIT needs the TCPInfo of the socket.
+func (l *TCPHealthLimits) Diagnose(si *SocketInfo) (findings []TCPFinding) {
+	add := func(problem int, format string, a ...interface{}) {
+		findings = append(findings, TCPFinding{Problem: problem, Reason: fmt.Sprintf(format, a...)})
+	}
+	if si.Status == SsCLOSEWAIT && si.RxQueue > 0 {
+		// the peer closed, but the application neither reads nor closes
+		add(TCPProblemCloseWait, "%d bytes unread after the peer closed", si.RxQueue)
+	}
+	t := si.TCPInfo
+	if t == nil {
+		return findings
+	}
+
+	var ratio float64
+	if t.Segs_out > 0 {
+		ratio = float64(t.Total_retrans) / float64(t.Segs_out)
+	}
+	if t.Retrans > 0 || t.Lost > 0 || (t.Segs_out >= l.MinSegsOut && ratio >= l.RetransRatio) {
+		add(TCPProblemRetransmitting, "retrans %d/%d lost %d total %d/%d segs (%.2f%%)",
+			t.Retrans, t.Total_retrans, t.Lost, t.Total_retrans, t.Segs_out, ratio*100)
+	}
+
+	limited := false
+	if t.Has(SizeOfTCPInfoSndbufLimited) && t.Busy_time > 0 {
+		if ratio = float64(t.Rwnd_limited) / float64(t.Busy_time); ratio >= l.LimitedRatio {
+			add(TCPProblemRwndLimited, "%.1f%% of %dms busy, snd_wnd %d", ratio*100, t.Busy_time/1000, t.Snd_wnd)
+			limited = true
+		}
+		if ratio = float64(t.Sndbuf_limited) / float64(t.Busy_time); ratio >= l.LimitedRatio {
+			add(TCPProblemSndbufLimited, "%.1f%% of %dms busy, notsent %d", ratio*100, t.Busy_time/1000, t.Notsent_bytes)
+			limited = true
+		}
+	}
+
+	// tcpi_probes counts keepalive probes as well, they only probe a zero
+	// window under the persist timer or when the peer window is 0
+	zeroWindow := si.Timer == timerPersist || (t.Has(SizeOfTCPInfoSndWnd) && t.Snd_wnd == 0)
+	if zeroWindow && (t.Probes > 0 || si.Probes > 0) {
+		probes := int(t.Probes)
+		if probes == 0 {
+			probes = si.Probes
+		}
+		add(TCPProblemZeroWindow, "%d unanswered probes, backoff %d", probes, t.Backoff)
+	}
+
+	if t.Rtt > 0 && t.Rttvar >= l.MinRttvar && float64(t.Rttvar) >= l.RttvarRatio*float64(t.Rtt) {
+		add(TCPProblemRttVariance, "rtt %.3f/%.3fms min %.3fms",
+			float64(t.Rtt)/1000, float64(t.Rttvar)/1000, float64(t.Min_rtt)/1000)
+	}
+
+	// an idle connection is always app limited, and one limited by a window
+	// or buffer already has a better reason
+	if t.Delivery_rate_app_limited && t.Busy_time > 0 && t.Last_data_sent < l.IdleAfter && !limited {
+		add(TCPProblemAppLimited, "delivery rate %sbps limited by the sender", BwToStr(float64(t.Delivery_rate)*8))
+	}
+	return findings
+}
+
+// DiagnoseTCP returns the TCP sockets of sis with problems beyond limits, in
+// the order of sis.
+func DiagnoseTCP(sis []SocketInfo, limits *TCPHealthLimits) (reports []TCPHealth) {
+	if limits == nil {
+		limits = &DefaultTCPHealthLimits
+	}
+	for i := range sis {
+		if sis[i].Protocal != ProtocalTCP {
+			continue
+		}
+		if findings := limits.Diagnose(&sis[i]); len(findings) > 0 {
+			reports = append(reports, TCPHealth{Info: sis[i], Findings: findings})
+		}
+	}
+	return reports
+}
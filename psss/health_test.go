@@ -0,0 +1,102 @@
+package psss
+
+import (
+	"reflect"
+	"testing"
+)
+
+const timerKeepalive = 2
+
+func TestDiagnose(t *testing.T) {
+	// a busy connection without problems, each case breaks one thing
+	healthy := func() TCPInfo {
+		return TCPInfo{
+			Length:         SizeOfTCPInfo,
+			State:          SsESTAB,
+			Segs_out:       1000,
+			Total_retrans:  1,
+			Rtt:            10000,
+			Rttvar:         2000,
+			Busy_time:      1000000,
+			Rwnd_limited:   10000,
+			Sndbuf_limited: 10000,
+			Snd_wnd:        65536,
+		}
+	}
+	for _, tt := range []struct {
+		name   string
+		status uint8
+		rxq    uint32
+		timer  int
+		probes int
+		info   func(t *TCPInfo)
+		want   []int
+	}{
+		{name: "healthy"},
+		{name: "retransmitting now", info: func(t *TCPInfo) { t.Retrans = 1 }, want: []int{TCPProblemRetransmitting}},
+		{name: "lost segments", info: func(t *TCPInfo) { t.Lost = 2 }, want: []int{TCPProblemRetransmitting}},
+		{name: "retrans ratio", info: func(t *TCPInfo) { t.Total_retrans = 10 }, want: []int{TCPProblemRetransmitting}},
+		{name: "too few segments for a ratio", info: func(t *TCPInfo) { t.Segs_out, t.Total_retrans = 50, 10 }},
+		{name: "rwnd limited", info: func(t *TCPInfo) { t.Rwnd_limited = 500000 }, want: []int{TCPProblemRwndLimited}},
+		{name: "sndbuf limited", info: func(t *TCPInfo) { t.Sndbuf_limited = 100000 }, want: []int{TCPProblemSndbufLimited}},
+		{name: "limits unknown to the kernel", info: func(t *TCPInfo) {
+			t.Length, t.Rwnd_limited = SizeOfTCPInfoDeliveryRate, 500000
+		}},
+		{name: "zero window under the persist timer", timer: timerPersist,
+			info: func(t *TCPInfo) { t.Probes = 3 }, want: []int{TCPProblemZeroWindow}},
+		{name: "persist timer from proc", timer: timerPersist, probes: 2, want: []int{TCPProblemZeroWindow}},
+		{name: "zero peer window", info: func(t *TCPInfo) { t.Probes, t.Snd_wnd = 1, 0 }, want: []int{TCPProblemZeroWindow}},
+		{name: "keepalive probes", timer: timerKeepalive, probes: 3, info: func(t *TCPInfo) { t.Probes = 3 }},
+		{name: "keepalive probes on an old kernel", timer: timerKeepalive,
+			info: func(t *TCPInfo) { t.Length, t.Probes, t.Snd_wnd = SizeOfTCPInfoReordSeen, 3, 0 }},
+		{name: "rtt variance", info: func(t *TCPInfo) { t.Rttvar = 12000 }, want: []int{TCPProblemRttVariance}},
+		{name: "small rtt variance", info: func(t *TCPInfo) { t.Rtt, t.Rttvar = 1000, 4000 }},
+		{name: "app limited", info: func(t *TCPInfo) { t.Delivery_rate_app_limited = true }, want: []int{TCPProblemAppLimited}},
+		{name: "app limited and idle", info: func(t *TCPInfo) {
+			t.Delivery_rate_app_limited, t.Last_data_sent = true, 5000
+		}},
+		{name: "app limited by the window", info: func(t *TCPInfo) {
+			t.Delivery_rate_app_limited, t.Rwnd_limited = true, 500000
+		}, want: []int{TCPProblemRwndLimited}},
+		{name: "stuck close-wait", status: SsCLOSEWAIT, rxq: 10, want: []int{TCPProblemCloseWait}},
+		{name: "drained close-wait", status: SsCLOSEWAIT},
+		{name: "several", info: func(t *TCPInfo) {
+			t.Retrans, t.Rttvar, t.Sndbuf_limited = 1, 20000, 200000
+		}, want: []int{TCPProblemRetransmitting, TCPProblemSndbufLimited, TCPProblemRttVariance}},
+	} {
+		info := healthy()
+		if tt.info != nil {
+			tt.info(&info)
+		}
+		si := SocketInfo{Status: SsESTAB, Timer: tt.timer, Probes: tt.probes, TCPInfo: &info}
+		if tt.status != 0 {
+			si.Status = tt.status
+		}
+		si.RxQueue = tt.rxq
+		var got []int
+		for _, f := range DefaultTCPHealthLimits.Diagnose(&si) {
+			got = append(got, f.Problem)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDiagnoseTCP(t *testing.T) {
+	sis := []SocketInfo{
+		{Protocal: ProtocalTCP, Status: SsESTAB, TCPInfo: &TCPInfo{Length: SizeOfTCPInfo, Snd_wnd: 1}},
+		{Protocal: ProtocalUDP, Status: SsCLOSEWAIT, RxQueue: 1},
+		{Protocal: ProtocalTCP, Status: SsCLOSEWAIT, RxQueue: 1},
+	}
+	reports := DiagnoseTCP(sis, nil)
+	if len(reports) != 1 {
+		t.Fatalf("got %d reports, want only the stuck TCP socket", len(reports))
+	}
+	if reports[0].Info.Protocal != ProtocalTCP || len(reports[0].Findings) != 1 {
+		t.Fatalf("got %+v", reports[0])
+	}
+	if got := reports[0].Findings[0].String(); got != "stuck_close_wait: 1 bytes unread after the peer closed" {
+		t.Errorf("got finding %q", got)
+	}
+}
@@ -5,7 +5,9 @@ package psss
 import (
 	"bufio"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
@@ -89,6 +91,20 @@ func (ds *DiskStat) Parse(raw string) (err error) {
 	return nil
 }
 
+func (ds *DiskStat) HWSectorSize() (uint64, error) {
+	return ds.HWSectorSizeFrom(SysRoot)
+}
+
+// HWSectorSizeFrom reads the hardware sector size of the disk from the sys
+// mounted at sysRoot.
+func (ds *DiskStat) HWSectorSizeFrom(sysRoot string) (uint64, error) {
+	raw, err := ioutil.ReadFile(fmt.Sprintf("%s/block/%s/queue/hw_sector_size", sysRoot, ds.Name))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(raw)), 10, 64)
+}
+
 type DiskStats []*DiskStat
 
 func NewDiskStats() DiskStats {
@@ -96,7 +112,12 @@ func NewDiskStats() DiskStats {
 }
 
 func (dss *DiskStats) Get() (err error) {
-	fd, err := os.Open(ProcRoot + "/diskstats")
+	return dss.GetFrom(ProcRoot)
+}
+
+// GetFrom reads diskstats of the proc mounted at procRoot.
+func (dss *DiskStats) GetFrom(procRoot string) (err error) {
+	fd, err := os.Open(procRoot + "/diskstats")
 	if err != nil {
 		return err
 	}
@@ -200,7 +221,13 @@ func NewNetDevs() NetDevs {
 }
 
 func (nds *NetDevs) Get() error {
-	fd, err := os.Open(ProcRoot + "/self/net/dev")
+	return nds.GetFrom(ProcRoot)
+}
+
+// GetFrom reads net/dev of the proc mounted at procRoot, for the namespace of
+// ProcPid.
+func (nds *NetDevs) GetFrom(procRoot string) error {
+	fd, err := os.Open(filepath.Join(procRoot, ProcPid, "net/dev"))
 	if err != nil {
 		return err
 	}
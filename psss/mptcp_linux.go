@@ -0,0 +1,42 @@
+// +build linux
+
+package psss
+
+const (
+	SizeOfMPTCPInfo = 96
+)
+
+const (
+	MPTCP_INFO_FLAG_FALLBACK            = 0x00000001
+	MPTCP_INFO_FLAG_REMOTE_KEY_RECEIVED = 0x00000002
+)
+
+// MPTCPInfo is struct mptcp_info, kernels before 6.x fill only a prefix of it.
+type MPTCPInfo struct {
+	Subflows           uint8
+	AddAddrSignal      uint8
+	AddAddrAccepted    uint8
+	SubflowsMax        uint8
+	AddAddrSignalMax   uint8
+	AddAddrAcceptedMax uint8
+	Pad                uint16
+	Flags              uint32
+	Token              uint32
+	WriteSeq           uint64
+	SndUna             uint64
+	RcvNxt             uint64
+	LocalAddrUsed      uint8
+	LocalAddrMax       uint8
+	CsumEnabled        uint8
+	Pad2               uint8
+	Retransmits        uint32
+	BytesRetrans       uint64
+	BytesSent          uint64
+	BytesReceived      uint64
+	BytesAcked         uint64
+	SubflowsTotal      uint8
+	Reserved           [3]uint8
+	LastDataSent       uint32
+	LastDataRecv       uint32
+	LastAckRecv        uint32
+}
@@ -0,0 +1,261 @@
+// +build linux
+
+package psss
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	SizeOfNetlinkDiagRequest = 36
+	SizeOfNetlinkDiagMsg     = 28
+)
+
+const (
+	NDIAG_SHOW_MEMINFO  = 0x00000001 /* show memory info of a socket */
+	NDIAG_SHOW_GROUPS   = 0x00000002 /* show groups of a netlink socket */
+	NDIAG_SHOW_RING_CFG = 0x00000004 /* show ring configuration */
+	NDIAG_SHOW_FLAGS    = 0x00000008 /* show flags of a netlink socket */
+	NDIAG_PROTO_ALL     = 0xff
+)
+
+const (
+	NETLINK_DIAG_MEMINFO = iota
+	NETLINK_DIAG_GROUPS
+	NETLINK_DIAG_RX_RING
+	NETLINK_DIAG_TX_RING
+	NETLINK_DIAG_FLAGS
+	NETLINK_DIAG_MAX
+)
+
+const (
+	NDIAG_FLAG_CB_RUNNING      = 0x00000001
+	NDIAG_FLAG_PKTINFO         = 0x00000002
+	NDIAG_FLAG_BROADCAST_ERROR = 0x00000004
+	NDIAG_FLAG_NO_ENOBUFS      = 0x00000008
+	NDIAG_FLAG_LISTEN_ALL_NSID = 0x00000010
+	NDIAG_FLAG_CAP_ACK         = 0x00000020
+)
+
+var (
+	NetlinkProtocolName = map[uint8]string{
+		unix.NETLINK_ROUTE:          "route",
+		unix.NETLINK_UNUSED:         "unused",
+		unix.NETLINK_USERSOCK:       "usersock",
+		unix.NETLINK_FIREWALL:       "firewall",
+		unix.NETLINK_SOCK_DIAG:      "sock_diag",
+		unix.NETLINK_NFLOG:          "nflog",
+		unix.NETLINK_XFRM:           "xfrm",
+		unix.NETLINK_SELINUX:        "selinux",
+		unix.NETLINK_ISCSI:          "iscsi",
+		unix.NETLINK_AUDIT:          "audit",
+		unix.NETLINK_FIB_LOOKUP:     "fib_lookup",
+		unix.NETLINK_CONNECTOR:      "connector",
+		unix.NETLINK_NETFILTER:      "netfilter",
+		unix.NETLINK_IP6_FW:         "ip6_fw",
+		unix.NETLINK_DNRTMSG:        "dnrtmsg",
+		unix.NETLINK_KOBJECT_UEVENT: "uevent",
+		unix.NETLINK_GENERIC:        "generic",
+		unix.NETLINK_SCSITRANSPORT:  "scsitransport",
+		unix.NETLINK_ECRYPTFS:       "ecryptfs",
+		unix.NETLINK_RDMA:           "rdma",
+		unix.NETLINK_CRYPTO:         "crypto",
+		unix.NETLINK_SMC:            "smc",
+	}
+)
+
+type NetlinkDiagReq struct {
+	SdiagFamily   uint8
+	SdiagProtocol uint8
+	Pad           uint16
+	NdiagIno      uint32
+	NdiagShow     uint32
+	NdiagCookie   [2]uint32
+}
+
+type NetlinkDiagRequest struct {
+	Header  unix.NlMsghdr
+	Request NetlinkDiagReq
+}
+
+type NetlinkDiagMessage struct {
+	NdiagFamily    uint8
+	NdiagType      uint8
+	NdiagProtocol  uint8
+	NdiagState     uint8
+	NdiagPortid    uint32
+	NdiagDstPortid uint32
+	NdiagDstGroup  uint32
+	NdiagIno       uint32
+	NdiagCookie    [2]uint32
+}
+
+func NetlinkProtocolString(protocol uint8) string {
+	if name, ok := NetlinkProtocolName[protocol]; ok {
+		return name
+	}
+	return strconv.Itoa(int(protocol))
+}
+
+func newNetlinkDiagRequest(show uint32) []byte {
+	var req NetlinkDiagRequest
+	req.Header.Type = SOCK_DIAG_BY_FAMILY
+	req.Header.Flags = unix.NLM_F_DUMP | unix.NLM_F_REQUEST
+	req.Header.Len = SizeOfNetlinkDiagRequest
+	req.Request.SdiagFamily = unix.AF_NETLINK
+	req.Request.SdiagProtocol = NDIAG_PROTO_ALL
+	req.Request.NdiagShow = show
+	request := make([]byte, SizeOfNetlinkDiagRequest)
+	*(*NetlinkDiagRequest)(unsafe.Pointer(&request[0])) = req
+	return request
+}
+
+func parseNetlinkDiagMsg(data []byte, si *SocketInfo) error {
+	var (
+		cursor int
+		nlAttr unix.NlAttr
+	)
+	if len(data) < SizeOfNetlinkDiagMsg {
+		return fmt.Errorf("%w:[netlink_diag, %d bytes]", ErrTruncatedMessage, len(data))
+	}
+	nDiagMsg := *(*NetlinkDiagMessage)(unsafe.Pointer(&data[0]))
+	// sockets start in TCP_CLOSE and become NETLINK_CONNECTED, which is
+	// TCP_ESTABLISHED, after connect
+	si.Status = nDiagMsg.NdiagState
+	if si.Status == 0 || si.Status >= SsMAX {
+		si.Status = SsUNCONN
+	}
+	si.Type = nDiagMsg.NdiagType
+	si.Inode = nDiagMsg.NdiagIno
+	si.SK = uint64(nDiagMsg.NdiagCookie[1])<<32 | uint64(nDiagMsg.NdiagCookie[0])
+	si.NetlinkProtocol = nDiagMsg.NdiagProtocol
+	si.LocalAddr.Name = NetlinkProtocolString(nDiagMsg.NdiagProtocol)
+	si.LocalAddr.ID = nDiagMsg.NdiagPortid
+	si.RemoteAddr.ID = nDiagMsg.NdiagDstPortid
+	cursor = SizeOfNetlinkDiagMsg
+	for cursor+unix.SizeofNlAttr <= len(data) {
+		nlAttr = *(*unix.NlAttr)(unsafe.Pointer(&data[cursor]))
+		if nlAttr.Len < unix.SizeofNlAttr || cursor+int(nlAttr.Len) > len(data) {
+			break
+		}
+		payload := data[cursor+unix.SizeofNlAttr : cursor+int(nlAttr.Len)]
+		switch nlAttr.Type {
+		case NETLINK_DIAG_MEMINFO:
+			si.Meminfo = make([]uint32, 0, len(payload)/4)
+			for j := 0; j+4 <= len(payload); j += 4 {
+				si.Meminfo = append(si.Meminfo, *(*uint32)(unsafe.Pointer(&payload[j])))
+			}
+		case NETLINK_DIAG_GROUPS:
+			// a bitmap of unsigned longs, bit n is group n+1
+			for j := 0; j+4 <= len(payload); j += 4 {
+				word := *(*uint32)(unsafe.Pointer(&payload[j]))
+				for bit := uint32(0); word != 0; bit++ {
+					if word&1 != 0 {
+						si.NetlinkGroups = append(si.NetlinkGroups, uint32(j)*8+bit+1)
+					}
+					word >>= 1
+				}
+			}
+		case NETLINK_DIAG_FLAGS:
+			if len(payload) >= 4 {
+				si.NetlinkFlags = *(*uint32)(unsafe.Pointer(&payload[0]))
+			}
+		}
+		cursor += int(nlAttr.Len+unix.NLA_ALIGNTO-1) & ^(unix.NLA_ALIGNTO - 1)
+	}
+	return nil
+}
+
+// GenericNetlinkRead reads netlink sockets according to the package level
+// filters and flags.
+func GenericNetlinkRead() (sis []SocketInfo, err error) {
+	c := newClientFromFlags()
+	defer c.Close()
+	if sis, err = c.NetlinkRead(); err != nil && !errors.Is(err, ErrTruncatedMessage) {
+		return nil, err
+	}
+	return sis, err
+}
+
+// netlinkReadProc reads /proc/net/netlink, where only the first 32 groups
+// of a socket are shown.
+func (c *Client) netlinkReadProc(fn func(si *SocketInfo) bool) (err error) {
+	var (
+		fields    []string
+		tempInt64 int64
+		tempUint  uint64
+	)
+	file, err := c.openProc("Netlink")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// sk Eth Pid Groups Rmem Wmem Dump Locks Drops Inode
+		fields = strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[0] == "sk" {
+			continue
+		}
+		si := NewSocketInfo()
+		si.Protocal = ProtocalNetlink
+		si.Status = SsUNCONN
+		si.Type = unix.SOCK_RAW
+		if si.SK, err = strconv.ParseUint(fields[0], 16, 64); err != nil {
+			continue
+		}
+		if tempInt64, err = strconv.ParseInt(fields[1], 10, 32); err != nil {
+			continue
+		}
+		si.NetlinkProtocol = uint8(tempInt64)
+		si.LocalAddr.Name = NetlinkProtocolString(si.NetlinkProtocol)
+		if tempUint, err = strconv.ParseUint(fields[2], 10, 32); err != nil {
+			continue
+		}
+		si.LocalAddr.ID = uint32(tempUint)
+		if tempUint, err = strconv.ParseUint(fields[3], 16, 32); err != nil {
+			continue
+		}
+		for bit := uint32(0); tempUint != 0; bit++ {
+			if tempUint&1 != 0 {
+				si.NetlinkGroups = append(si.NetlinkGroups, bit+1)
+			}
+			tempUint >>= 1
+		}
+		if tempInt64, err = strconv.ParseInt(fields[4], 10, 64); err != nil {
+			continue
+		}
+		si.RxQueue = uint32(tempInt64)
+		if tempInt64, err = strconv.ParseInt(fields[5], 10, 64); err != nil {
+			continue
+		}
+		si.TxQueue = uint32(tempInt64)
+		if fields[6] != "0" {
+			si.NetlinkFlags |= NDIAG_FLAG_CB_RUNNING
+		}
+		if si.Drops, err = strconv.Atoi(fields[8]); err != nil {
+			continue
+		}
+		if tempInt64, err = strconv.ParseInt(fields[9], 10, 64); err != nil {
+			continue
+		}
+		si.Inode = uint32(tempInt64)
+		if c.States&(1<<si.Status) == 0 {
+			continue
+		}
+		if c.Process {
+			si.SetUpRelation()
+		}
+		if !fn(si) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
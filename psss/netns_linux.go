@@ -0,0 +1,176 @@
+// +build linux
+
+package psss
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+const NetNSRunDir = "/var/run/netns"
+
+// nsfs inodes are handed out from PROC_DYNAMIC_FIRST on, far above any pid
+const nsfsInodeFirst = 0xF0000000
+
+// NetNS is a network namespace reachable through a file, either a bind mount
+// made by "ip netns add" or /proc/<pid>/ns/net of a process inside it.
+type NetNS struct {
+	Name  string
+	Path  string
+	Inode uint64
+}
+
+func NetNSFromPath(name, path string) (*NetNS, error) {
+	var stat unix.Stat_t
+	if err := unix.Stat(path, &stat); err != nil {
+		return nil, fmt.Errorf("stat netns %s error:[%v]", path, err)
+	}
+	return &NetNS{Name: name, Path: path, Inode: stat.Ino}, nil
+}
+
+func NetNSFromName(name string) (*NetNS, error) {
+	return NetNSFromPath(name, NetNSRunDir+"/"+name)
+}
+
+func NetNSFromPid(pid int) (*NetNS, error) {
+	return NetNSFromPath("pid:"+strconv.Itoa(pid), fmt.Sprintf("%s/%d/ns/net", ProcRoot, pid))
+}
+
+func NetNSFromInode(inode uint64) (*NetNS, error) {
+	nss, err := ListNetNS()
+	if err != nil {
+		return nil, err
+	}
+	for _, ns := range nss {
+		if ns.Inode == inode {
+			return ns, nil
+		}
+	}
+	return nil, fmt.Errorf("netns with inode %d not found", inode)
+}
+
+// ParseNetNS accepts a path, a name under /var/run/netns, "pid:N", "inode:N"
+// or a bare number, which is an nsfs inode when large enough and a pid
+// otherwise.
+func ParseNetNS(spec string) (*NetNS, error) {
+	switch {
+	case strings.Contains(spec, "/"):
+		return NetNSFromPath(spec, spec)
+	case strings.HasPrefix(spec, "pid:"):
+		pid, err := strconv.Atoi(spec[len("pid:"):])
+		if err != nil {
+			return nil, fmt.Errorf("invalid netns pid:[%s]", spec)
+		}
+		return NetNSFromPid(pid)
+	case strings.HasPrefix(spec, "inode:"):
+		inode, err := strconv.ParseUint(spec[len("inode:"):], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid netns inode:[%s]", spec)
+		}
+		return NetNSFromInode(inode)
+	}
+	if number, err := strconv.ParseUint(spec, 10, 64); err == nil {
+		if number >= nsfsInodeFirst {
+			return NetNSFromInode(number)
+		}
+		return NetNSFromPid(int(number))
+	}
+	return NetNSFromName(spec)
+}
+
+// ListNetNS returns every network namespace that has a name or a process,
+// once each. Namespaces without a name are named after their first process.
+func ListNetNS() (nss []*NetNS, err error) {
+	seen := make(map[uint64]bool)
+	if entries, err := os.ReadDir(NetNSRunDir); err == nil {
+		for _, entry := range entries {
+			ns, err := NetNSFromName(entry.Name())
+			if err != nil || seen[ns.Inode] {
+				continue
+			}
+			seen[ns.Inode] = true
+			nss = append(nss, ns)
+		}
+	}
+	entries, err := os.ReadDir(ProcRoot)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		ns, err := NetNSFromPid(pid)
+		if err != nil || seen[ns.Inode] {
+			continue
+		}
+		seen[ns.Inode] = true
+		nss = append(nss, ns)
+	}
+	return nss, nil
+}
+
+// Do runs fn on a thread that was moved into the namespace. Sockets and proc
+// files opened by fn stay bound to the namespace after Do returns.
+func (ns *NetNS) Do(fn func() error) error {
+	result := make(chan error, 1)
+	// a goroutine of its own, so that a thread that cannot be moved back ends
+	// with it instead of running the caller in the wrong namespace
+	go func() {
+		runtime.LockOSThread()
+		origin, err := os.Open("/proc/thread-self/ns/net")
+		if err != nil {
+			runtime.UnlockOSThread()
+			result <- err
+			return
+		}
+		defer origin.Close()
+		target, err := os.Open(ns.Path)
+		if err != nil {
+			runtime.UnlockOSThread()
+			result <- err
+			return
+		}
+		defer target.Close()
+		if err = unix.Setns(int(target.Fd()), unix.CLONE_NEWNET); err != nil {
+			runtime.UnlockOSThread()
+			result <- fmt.Errorf("setns %s error:[%v]", ns.Path, err)
+			return
+		}
+		fnErr := fn()
+		if err = unix.Setns(int(origin.Fd()), unix.CLONE_NEWNET); err != nil {
+			// the thread is left locked, the runtime terminates it when the
+			// goroutine exits
+			result <- fmt.Errorf("restore netns error:[%v]", err)
+			return
+		}
+		runtime.UnlockOSThread()
+		result <- fnErr
+	}()
+	return <-result
+}
+
+// openProc opens a /proc/net file of the namespace of c.
+func (c *Client) openProc(key string) (file *os.File, err error) {
+	procPath, ok := procFilePath[key]
+	if !ok {
+		return nil, fmt.Errorf("no proc file for %s", key)
+	}
+	if c.NetNS == nil {
+		return os.Open(filepath.Join(c.ProcRoot, procPath))
+	}
+	// /proc/net follows the main thread, thread-self follows the one in Do
+	path := filepath.Join(c.ProcRoot, "thread-self", procPath)
+	err = c.NetNS.Do(func() error {
+		file, err = os.Open(path)
+		return err
+	})
+	return file, err
+}
@@ -0,0 +1,282 @@
+// +build linux
+
+package psss
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	SizeOfPacketDiagRequest = 36
+	SizeOfPacketDiagMsg     = 16
+)
+
+const (
+	PACKET_SHOW_INFO     = 0x00000001 /* Basic packet_sk information */
+	PACKET_SHOW_MCLIST   = 0x00000002 /* A set of packet_diag_mclist-s */
+	PACKET_SHOW_RING_CFG = 0x00000004 /* Rings configuration parameters */
+	PACKET_SHOW_FANOUT   = 0x00000008
+	PACKET_SHOW_MEMINFO  = 0x00000010
+	PACKET_SHOW_FILTER   = 0x00000020
+)
+
+const (
+	PACKET_DIAG_INFO = iota
+	PACKET_DIAG_MCLIST
+	PACKET_DIAG_RX_RING
+	PACKET_DIAG_TX_RING
+	PACKET_DIAG_FANOUT
+	PACKET_DIAG_UID
+	PACKET_DIAG_MEMINFO
+	PACKET_DIAG_FILTER
+	PACKET_DIAG_MAX
+)
+
+const (
+	PDI_RUNNING = 0x1
+	PDI_AUXDATA = 0x2
+	PDI_ORIGDEV = 0x4
+	PDI_VNETHDR = 0x8
+	PDI_LOSS    = 0x10
+)
+
+var (
+	PacketFanoutType = []string{
+		"hash",
+		"lb",
+		"cpu",
+		"rollover",
+		"rnd",
+		"qm",
+		"cbpf",
+		"ebpf",
+	}
+
+	EthProtocolName = map[uint16]string{
+		unix.ETH_P_802_3:  "802_3",
+		unix.ETH_P_ALL:    "ALL",
+		unix.ETH_P_802_2:  "802_2",
+		unix.ETH_P_IP:     "IP",
+		unix.ETH_P_ARP:    "ARP",
+		unix.ETH_P_RARP:   "RARP",
+		unix.ETH_P_8021Q:  "802_1Q",
+		unix.ETH_P_IPV6:   "IPV6",
+		unix.ETH_P_PAE:    "PAE",
+		unix.ETH_P_8021AD: "802_1AD",
+		unix.ETH_P_LLDP:   "LLDP",
+		unix.ETH_P_1588:   "1588",
+	}
+)
+
+type PacketDiagReq struct {
+	SdiagFamily   uint8
+	SdiagProtocol uint8
+	Pad           uint16
+	PdiagIno      uint32
+	PdiagShow     uint32
+	PdiagCookie   [2]uint32
+}
+
+type PacketDiagRequest struct {
+	Header  unix.NlMsghdr
+	Request PacketDiagReq
+}
+
+type PacketDiagMessage struct {
+	PdiagFamily uint8
+	PdiagType   uint8
+	PdiagNum    uint16
+	PdiagIno    uint32
+	PdiagCookie [2]uint32
+}
+
+type PacketDiagInfo struct {
+	Index      uint32
+	Version    uint32
+	Reserve    uint32
+	CopyThresh uint32
+	Tstamp     uint32
+	Flags      uint32
+}
+
+type PacketDiagMclist struct {
+	Index uint32
+	Count uint32
+	Type  uint16
+	Alen  uint16
+	Addr  [32]uint8
+}
+
+type PacketDiagRing struct {
+	BlockSize  uint32
+	BlockNr    uint32
+	FrameSize  uint32
+	FrameNr    uint32
+	RetireTmo  uint32
+	SizeofPriv uint32
+	Features   uint32
+}
+
+func EthProtocolString(protocol uint16) string {
+	if name, ok := EthProtocolName[protocol]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", protocol)
+}
+
+func newPacketDiagRequest(show uint32) []byte {
+	var req PacketDiagRequest
+	req.Header.Type = SOCK_DIAG_BY_FAMILY
+	req.Header.Flags = unix.NLM_F_DUMP | unix.NLM_F_REQUEST
+	req.Header.Len = SizeOfPacketDiagRequest
+	req.Request.SdiagFamily = unix.AF_PACKET
+	req.Request.PdiagShow = show
+	request := make([]byte, SizeOfPacketDiagRequest)
+	*(*PacketDiagRequest)(unsafe.Pointer(&request[0])) = req
+	return request
+}
+
+func parsePacketDiagMsg(data []byte, si *SocketInfo) error {
+	var (
+		cursor int
+		nlAttr unix.NlAttr
+	)
+	if len(data) < SizeOfPacketDiagMsg {
+		return fmt.Errorf("%w:[packet_diag, %d bytes]", ErrTruncatedMessage, len(data))
+	}
+	pDiagMsg := *(*PacketDiagMessage)(unsafe.Pointer(&data[0]))
+	si.Status = SsUNCONN
+	si.Type = pDiagMsg.PdiagType
+	si.Inode = pDiagMsg.PdiagIno
+	si.SK = uint64(pDiagMsg.PdiagCookie[1])<<32 | uint64(pDiagMsg.PdiagCookie[0])
+	si.PacketProtocol = pDiagMsg.PdiagNum
+	si.LocalAddr.Name = EthProtocolString(pDiagMsg.PdiagNum)
+	cursor = SizeOfPacketDiagMsg
+	for cursor+unix.SizeofNlAttr <= len(data) {
+		nlAttr = *(*unix.NlAttr)(unsafe.Pointer(&data[cursor]))
+		if nlAttr.Len < unix.SizeofNlAttr || cursor+int(nlAttr.Len) > len(data) {
+			break
+		}
+		payload := data[cursor+unix.SizeofNlAttr : cursor+int(nlAttr.Len)]
+		switch nlAttr.Type {
+		case PACKET_DIAG_INFO:
+			si.PacketInfo = new(PacketDiagInfo)
+			copy((*[unsafe.Sizeof(PacketDiagInfo{})]byte)(unsafe.Pointer(si.PacketInfo))[:], payload)
+			si.LocalAddr.IfIndex = si.PacketInfo.Index
+		case PACKET_DIAG_MCLIST:
+			for i := 0; i+int(unsafe.Sizeof(PacketDiagMclist{})) <= len(payload); i += int(unsafe.Sizeof(PacketDiagMclist{})) {
+				si.PacketMclist = append(si.PacketMclist, *(*PacketDiagMclist)(unsafe.Pointer(&payload[i])))
+			}
+		case PACKET_DIAG_RX_RING:
+			si.PacketRxRing = new(PacketDiagRing)
+			copy((*[unsafe.Sizeof(PacketDiagRing{})]byte)(unsafe.Pointer(si.PacketRxRing))[:], payload)
+		case PACKET_DIAG_TX_RING:
+			si.PacketTxRing = new(PacketDiagRing)
+			copy((*[unsafe.Sizeof(PacketDiagRing{})]byte)(unsafe.Pointer(si.PacketTxRing))[:], payload)
+		case PACKET_DIAG_FANOUT:
+			if len(payload) >= 4 {
+				si.PacketFanout = new(uint32)
+				*si.PacketFanout = *(*uint32)(unsafe.Pointer(&payload[0]))
+			}
+		case PACKET_DIAG_UID:
+			if len(payload) >= 4 {
+				si.UID = uint64(*(*uint32)(unsafe.Pointer(&payload[0])))
+			}
+		case PACKET_DIAG_MEMINFO:
+			si.Meminfo = make([]uint32, 0, len(payload)/4)
+			for j := 0; j+4 <= len(payload); j += 4 {
+				si.Meminfo = append(si.Meminfo, *(*uint32)(unsafe.Pointer(&payload[j])))
+			}
+		case PACKET_DIAG_FILTER:
+			si.PacketFilter = make([]unix.SockFilter, len(payload)/unix.SizeofSockFilter)
+			for j := range si.PacketFilter {
+				si.PacketFilter[j] = *(*unix.SockFilter)(unsafe.Pointer(&payload[j*unix.SizeofSockFilter]))
+			}
+		}
+		cursor += int(nlAttr.Len+unix.NLA_ALIGNTO-1) & ^(unix.NLA_ALIGNTO - 1)
+	}
+	return nil
+}
+
+// GenericPacketRead reads packet sockets according to the package level
+// filters and flags.
+func GenericPacketRead() (sis []SocketInfo, err error) {
+	c := newClientFromFlags()
+	defer c.Close()
+	if sis, err = c.PacketRead(); err != nil && !errors.Is(err, ErrTruncatedMessage) {
+		return nil, err
+	}
+	return sis, err
+}
+
+// packetReadProc reads /proc/net/packet, which only knows the protocol,
+// the interface and the owner of a socket.
+func (c *Client) packetReadProc(fn func(si *SocketInfo) bool) (err error) {
+	var (
+		fields    []string
+		tempInt64 int64
+	)
+	if c.States&(1<<SsUNCONN) == 0 {
+		return nil
+	}
+	file, err := c.openProc("Packet")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// sk RefCnt Type Proto Iface R Rmem User Inode
+		fields = strings.Fields(scanner.Text())
+		if len(fields) < 9 || fields[0] == "sk" {
+			continue
+		}
+		si := NewSocketInfo()
+		si.Protocal = ProtocalPacket
+		si.Status = SsUNCONN
+		if si.SK, err = strconv.ParseUint(fields[0], 16, 64); err != nil {
+			continue
+		}
+		if si.RefCount, err = strconv.Atoi(fields[1]); err != nil {
+			continue
+		}
+		if tempInt64, err = strconv.ParseInt(fields[2], 10, 32); err != nil {
+			continue
+		}
+		si.Type = uint8(tempInt64)
+		if tempInt64, err = strconv.ParseInt(fields[3], 16, 32); err != nil {
+			continue
+		}
+		si.PacketProtocol = uint16(tempInt64)
+		si.LocalAddr.Name = EthProtocolString(si.PacketProtocol)
+		if tempInt64, err = strconv.ParseInt(fields[4], 10, 32); err != nil {
+			continue
+		}
+		si.LocalAddr.IfIndex = uint32(tempInt64)
+		if tempInt64, err = strconv.ParseInt(fields[6], 10, 64); err != nil {
+			continue
+		}
+		si.RxQueue = uint32(tempInt64)
+		if si.UID, err = strconv.ParseUint(fields[7], 10, 64); err != nil {
+			continue
+		}
+		if tempInt64, err = strconv.ParseInt(fields[8], 10, 64); err != nil {
+			continue
+		}
+		si.Inode = uint32(tempInt64)
+		if c.Process {
+			si.SetUpRelation()
+		}
+		if !fn(si) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
@@ -1,12 +1,21 @@
 package psss
 
 type ProcInfo struct {
-	Cmdline []string
-	Stat    ProcStat
-	IsEnd   bool
+	ProcRoot string // proc the process is read from
+	Cmdline  []string
+	Stat     ProcStat
+	IsEnd    bool
+}
+
+// SocketOwner is one process file descriptor that refers to a socket.
+type SocketOwner struct {
+	Name string `json:"name"`
+	Pid  int    `json:"pid"`
+	Fd   int    `json:"fd"`
 }
 
 func NewProcInfo() *ProcInfo {
 	p := new(ProcInfo)
+	p.ProcRoot = ProcRoot
 	return p
 }
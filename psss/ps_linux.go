@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"syscall"
@@ -81,7 +82,7 @@ type ProcStat struct {
 }
 
 func (p *ProcInfo) GetCmdline() error {
-	raw, err := ioutil.ReadFile(ProcRoot + fmt.Sprintf("/%d/cmdline", p.Stat.Pid))
+	raw, err := ioutil.ReadFile(p.ProcRoot + fmt.Sprintf("/%d/cmdline", p.Stat.Pid))
 	if err != nil {
 		return err
 	}
@@ -90,7 +91,7 @@ func (p *ProcInfo) GetCmdline() error {
 }
 
 func (p *ProcInfo) GetStat() (err error) {
-	fd, err := os.Open(ProcRoot + fmt.Sprintf("/%d/stat", p.Stat.Pid))
+	fd, err := os.Open(p.ProcRoot + fmt.Sprintf("/%d/stat", p.Stat.Pid))
 	if err != nil {
 		return err
 	}
@@ -139,17 +140,14 @@ func (p *ProcInfo) GetStat() (err error) {
 }
 
 func (p *ProcInfo) GetFds() (err error) {
-	fdPath := ProcRoot + fmt.Sprintf("/%d/fd", p.Stat.Pid)
+	fdPath := p.ProcRoot + fmt.Sprintf("/%d/fd", p.Stat.Pid)
 	file, err := os.Open(fdPath)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 	go fdDirentReader.Scan(file)
-	var (
-		fd Fd
-		ok bool
-	)
+	var fd int
 	for fdDirentReader.ExternalDirent = range fdDirentReader.DataChan {
 		if fdDirentReader.ExternalDirent.IsEnd {
 			return
@@ -157,25 +155,43 @@ func (p *ProcInfo) GetFds() (err error) {
 		if err = syscall.Stat(fdPath+"/"+fdDirentReader.ExternalDirent.Name, fdStat); err != nil {
 			continue
 		}
-		if _, ok = GlobalProcFds[p.Stat.Name]; !ok {
-			GlobalProcFds[p.Stat.Name] = make(map[int]map[uint32]Fd)
+		if fdStat.Mode&syscall.S_IFMT != syscall.S_IFSOCK {
+			continue
 		}
-		if _, ok = GlobalProcFds[p.Stat.Name][p.Stat.Pid]; !ok {
-			GlobalProcFds[p.Stat.Name][p.Stat.Pid] = make(map[uint32]Fd)
+		if fd, err = strconv.Atoi(fdDirentReader.ExternalDirent.Name); err != nil {
+			continue
 		}
-		fd.Name = fdDirentReader.ExternalDirent.Name
-		fd.Fresh = true
-
-		GlobalProcFds[p.Stat.Name][p.Stat.Pid][uint32(fdStat.Ino)] = fd
+		inode := uint32(fdStat.Ino)
+		scanSocketOwners[inode] = append(scanSocketOwners[inode], SocketOwner{Name: p.Stat.Name, Pid: p.Stat.Pid, Fd: fd})
 	}
 	return nil
 }
 
 func ScanProcFS(fdFlag bool) {
+	ScanProcFSFrom(ProcRoot, fdFlag)
+}
+
+// ScanProcFSFrom is ScanProcFS for the proc mounted at procRoot.
+func ScanProcFSFrom(procRoot string, fdFlag bool) {
+	if fdFlag {
+		scanSocketOwners = make(map[uint32][]SocketOwner)
+	}
 	defer func() {
+		if fdFlag {
+			for _, owners := range scanSocketOwners {
+				sort.Slice(owners, func(i, j int) bool {
+					if owners[i].Pid != owners[j].Pid {
+						return owners[i].Pid < owners[j].Pid
+					}
+					return owners[i].Fd < owners[j].Fd
+				})
+			}
+			GlobalSocketOwners = scanSocketOwners
+			scanSocketOwners = nil
+		}
 		ProcInfoChan <- &ProcInfo{IsEnd: true}
 	}()
-	fd, err := os.Open(ProcRoot)
+	fd, err := os.Open(procRoot)
 	if err != nil {
 		return
 	}
@@ -187,6 +203,7 @@ func ScanProcFS(fdFlag bool) {
 			return
 		}
 		proc := NewProcInfo()
+		proc.ProcRoot = procRoot
 		if proc.Stat.Pid, err = strconv.Atoi(procDirentReader.ExternalDirent.Name); err != nil {
 			continue
 		}
@@ -206,12 +223,17 @@ func ScanProcFS(fdFlag bool) {
 }
 
 func GetProcInfo(nameSet map[string]bool, fdFlag bool) map[string]map[int]*ProcInfo {
+	return GetProcInfoFrom(ProcRoot, nameSet, fdFlag)
+}
+
+// GetProcInfoFrom is GetProcInfo for the proc mounted at procRoot.
+func GetProcInfoFrom(procRoot string, nameSet map[string]bool, fdFlag bool) map[string]map[int]*ProcInfo {
 	defer recover()
 
 	var ok bool
 	var rProcName string
 	pi := make(map[string]map[int]*ProcInfo)
-	go ScanProcFS(fdFlag)
+	go ScanProcFSFrom(procRoot, fdFlag)
 	for proc := range ProcInfoChan {
 		if proc.IsEnd {
 			return pi
@@ -240,30 +262,3 @@ func GetProcInfo(nameSet map[string]bool, fdFlag bool) map[string]map[int]*ProcI
 	}
 	return pi
 }
-
-func CleanGlobalProcFds() {
-	var (
-		map2L map[uint32]Fd
-		pid   int
-		inode uint32
-		fd    Fd
-	)
-	for name, map1L := range GlobalProcFds {
-		for pid, map2L = range map1L {
-			for inode, fd = range map2L {
-				if fd.Fresh {
-					fd.Fresh = false
-					GlobalProcFds[name][pid][inode] = fd
-				} else {
-					delete(GlobalProcFds[name][pid], inode)
-					if len(GlobalProcFds[name][pid]) == 0 {
-						delete(GlobalProcFds[name], pid)
-					}
-					if len(GlobalProcFds[name]) == 0 {
-						delete(GlobalProcFds, name)
-					}
-				}
-			}
-		}
-	}
-}
@@ -0,0 +1,85 @@
+package psss
+
+import (
+	"sort"
+	"time"
+)
+
+// SocketRate is what a TCP connection did between two reads, derived from
+// the cumulative counters of TCPInfo. Info is the later read.
+type SocketRate struct {
+	Info          SocketInfo
+	Interval      time.Duration
+	BytesAcked    uint64
+	BytesReceived uint64
+	SegsOut       uint32
+	Retrans       uint32
+	TxBps         float64 // bits per second, acknowledged by the peer
+	RxBps         float64
+	RetransRatio  float64 // retransmitted segments per sent segment
+	RttDelta      int64   // change of the smoothed rtt in usec
+}
+
+// Bandwidth is the sum of both directions in bits per second.
+func (r *SocketRate) Bandwidth() float64 {
+	return r.TxBps + r.RxBps
+}
+
+// counterDelta returns cur-prev, or 0 when the counter went backwards. It is
+// for the 64-bit counters, which do not wrap, the 32-bit ones of the same
+// socket only go backwards by wrapping and are subtracted modulo 2^32.
+func counterDelta(prev, cur uint64) uint64 {
+	if cur < prev {
+		return 0
+	}
+	return cur - prev
+}
+
+// ComputeRates matches the sockets of two reads by cookie, SocketInfo.SK,
+// and returns the rates of the ones found in both with TCPInfo. Connections
+// that were opened or closed in between are left out.
+func ComputeRates(prev, cur []SocketInfo, interval time.Duration) (rates []SocketRate) {
+	if interval <= 0 {
+		return nil
+	}
+	before := make(map[uint64]*TCPInfo, len(prev))
+	for i := range prev {
+		if prev[i].TCPInfo != nil {
+			before[prev[i].SK] = prev[i].TCPInfo
+		}
+	}
+	seconds := interval.Seconds()
+	for i := range cur {
+		now := cur[i].TCPInfo
+		if now == nil {
+			continue
+		}
+		then, ok := before[cur[i].SK]
+		if !ok {
+			continue
+		}
+		r := SocketRate{
+			Info:          cur[i],
+			Interval:      interval,
+			BytesAcked:    counterDelta(then.Bytes_acked, now.Bytes_acked),
+			BytesReceived: counterDelta(then.Bytes_received, now.Bytes_received),
+			SegsOut:       now.Segs_out - then.Segs_out,
+			Retrans:       now.Total_retrans - then.Total_retrans,
+			RttDelta:      int64(now.Rtt) - int64(then.Rtt),
+		}
+		r.TxBps = float64(r.BytesAcked) * 8 / seconds
+		r.RxBps = float64(r.BytesReceived) * 8 / seconds
+		if r.SegsOut > 0 {
+			r.RetransRatio = float64(r.Retrans) / float64(r.SegsOut)
+		}
+		rates = append(rates, r)
+	}
+	return rates
+}
+
+// SortRatesByBandwidth puts the busiest connections first.
+func SortRatesByBandwidth(rates []SocketRate) {
+	sort.SliceStable(rates, func(i, j int) bool {
+		return rates[i].Bandwidth() > rates[j].Bandwidth()
+	})
+}
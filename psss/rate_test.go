@@ -0,0 +1,87 @@
+package psss
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func rateSocket(sk uint64, info TCPInfo) SocketInfo {
+	return SocketInfo{SK: sk, TCPInfo: &info}
+}
+
+func TestComputeRates(t *testing.T) {
+	prev := []SocketInfo{
+		rateSocket(1, TCPInfo{Bytes_acked: 1000, Bytes_received: 500, Segs_out: 10, Total_retrans: 1, Rtt: 2000}),
+		rateSocket(2, TCPInfo{Bytes_acked: 1 << 20, Segs_out: 100}),
+		rateSocket(3, TCPInfo{Bytes_acked: 7}), // closed before the second read
+		{SK: 4},                                // no TCPInfo, e.g. a listener
+	}
+	cur := []SocketInfo{
+		rateSocket(2, TCPInfo{Bytes_acked: 2 << 20, Segs_out: 200}),
+		rateSocket(1, TCPInfo{Bytes_acked: 3000, Bytes_received: 1500, Segs_out: 30, Total_retrans: 3, Rtt: 1500}),
+		rateSocket(5, TCPInfo{Bytes_acked: 1 << 30}), // opened after the first read
+		{SK: 4},
+	}
+	rates := ComputeRates(prev, cur, 2*time.Second)
+	if len(rates) != 2 {
+		t.Fatalf("got %d rates, want the 2 connections in both reads", len(rates))
+	}
+	// the order is that of cur, each socket against its own earlier read
+	r := rates[1]
+	if r.Info.SK != 1 || r.Interval != 2*time.Second {
+		t.Fatalf("got sk %d interval %v, want sk 1 and 2s", r.Info.SK, r.Interval)
+	}
+	if r.BytesAcked != 2000 || r.BytesReceived != 1000 || r.SegsOut != 20 || r.Retrans != 2 || r.RttDelta != -500 {
+		t.Errorf("got acked %d received %d segs %d retrans %d rtt %d, want 2000 1000 20 2 -500",
+			r.BytesAcked, r.BytesReceived, r.SegsOut, r.Retrans, r.RttDelta)
+	}
+	if r.TxBps != 8000 || r.RxBps != 4000 || r.Bandwidth() != 12000 || r.RetransRatio != 0.1 {
+		t.Errorf("got tx %v rx %v bandwidth %v ratio %v, want 8000 4000 12000 0.1",
+			r.TxBps, r.RxBps, r.Bandwidth(), r.RetransRatio)
+	}
+	if rates[0].Info.SK != 2 || rates[0].BytesAcked != 1<<20 || rates[0].RetransRatio != 0 {
+		t.Errorf("got sk %d acked %d ratio %v, want sk 2 acked %d ratio 0",
+			rates[0].Info.SK, rates[0].BytesAcked, rates[0].RetransRatio, 1<<20)
+	}
+
+	SortRatesByBandwidth(rates)
+	if rates[0].Info.SK != 2 {
+		t.Errorf("got sk %d first, want the busiest, 2", rates[0].Info.SK)
+	}
+
+	if rates := ComputeRates(prev, cur, 0); rates != nil {
+		t.Errorf("got %d rates without an interval", len(rates))
+	}
+}
+
+func TestComputeRatesCounterReset(t *testing.T) {
+	for _, tt := range []struct {
+		name              string
+		then, now         TCPInfo
+		acked, segs, retr uint64
+	}{
+		{
+			name: "32-bit counters wrap",
+			then: TCPInfo{Segs_out: math.MaxUint32 - 9, Total_retrans: math.MaxUint32},
+			now:  TCPInfo{Segs_out: 10, Total_retrans: 1},
+			segs: 20, retr: 2,
+		},
+		{
+			// a 64-bit counter only goes back when the socket was replaced
+			name: "64-bit counter goes back",
+			then: TCPInfo{Bytes_acked: 5000, Segs_out: 5},
+			now:  TCPInfo{Bytes_acked: 1000, Segs_out: 5},
+		},
+	} {
+		rates := ComputeRates([]SocketInfo{rateSocket(1, tt.then)}, []SocketInfo{rateSocket(1, tt.now)}, time.Second)
+		if len(rates) != 1 {
+			t.Fatalf("%s: got %d rates, want 1", tt.name, len(rates))
+		}
+		r := rates[0]
+		if r.BytesAcked != tt.acked || uint64(r.SegsOut) != tt.segs || uint64(r.Retrans) != tt.retr {
+			t.Errorf("%s: got acked %d segs %d retrans %d, want %d %d %d",
+				tt.name, r.BytesAcked, r.SegsOut, r.Retrans, tt.acked, tt.segs, tt.retr)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package psss
+
+import (
+	"strconv"
+)
+
+// SocketRecord is the serializable form of a SocketInfo. The json names are
+// part of the output format and must not change.
+type SocketRecord struct {
+	Netns     string         `json:"netns,omitempty"`
+	Source    string         `json:"source,omitempty"`
+	State     string         `json:"state"`
+	RecvQ     uint32         `json:"recv_q"`
+	SendQ     uint32         `json:"send_q"`
+	Local     string         `json:"local"`
+	Remote    string         `json:"remote"`
+	UID       uint64         `json:"uid"`
+	Inode     uint32         `json:"inode"`
+	Cookie    string         `json:"cookie"`
+	Shutdown  uint8          `json:"shutdown,omitempty"`
+	TOS       uint8          `json:"tos,omitempty"`
+	TClass    uint8          `json:"tclass,omitempty"`
+	Mark      uint32         `json:"mark,omitempty"`
+	CgroupID  uint64         `json:"cgroup_id,omitempty"`
+	Timer     *TimerRecord   `json:"timer,omitempty"`
+	Meminfo   *MeminfoRecord `json:"meminfo,omitempty"`
+	Cong      string         `json:"cong,omitempty"`
+	TCPInfo   *TCPInfo       `json:"tcp_info,omitempty"`
+	VegasInfo *TCPVegasInfo  `json:"vegas_info,omitempty"`
+	BBRInfo   *TCPBBRInfo    `json:"bbr_info,omitempty"`
+	DCTCPInfo *TCPDCTCPInfo  `json:"dctcp_info,omitempty"`
+	Owners    []SocketOwner  `json:"owners,omitempty"`
+	// unix only
+	VFSDev     uint32        `json:"vfs_dev,omitempty"`
+	VFSInode   uint32        `json:"vfs_inode,omitempty"`
+	Pending    []uint32      `json:"pending,omitempty"`
+	PeerOwners []SocketOwner `json:"peer_owners,omitempty"`
+}
+
+type TimerRecord struct {
+	Name       string `json:"name"`
+	ExpiresSec int    `json:"expires_sec"`
+	Retransmit int    `json:"retransmit"`
+	Probes     int    `json:"probes"`
+}
+
+// MeminfoRecord uses the SK_MEMINFO_* names, sockets which only report
+// INET_DIAG_MEMINFO fill rmem_alloc, wmem_alloc, fwd_alloc and wmem_queued.
+type MeminfoRecord struct {
+	RmemAlloc  uint32 `json:"rmem_alloc"`
+	Rcvbuf     uint32 `json:"rcvbuf"`
+	WmemAlloc  uint32 `json:"wmem_alloc"`
+	Sndbuf     uint32 `json:"sndbuf"`
+	FwdAlloc   uint32 `json:"fwd_alloc"`
+	WmemQueued uint32 `json:"wmem_queued"`
+	Optmem     uint32 `json:"optmem"`
+	Backlog    uint32 `json:"backlog"`
+	Drops      uint32 `json:"drops"`
+}
+
+func (si *SocketInfo) Record() *SocketRecord {
+	r := &SocketRecord{
+		Netns:      si.NetNS,
+		Source:     SourceName[si.Source],
+		State:      si.StateString(),
+		RecvQ:      si.RxQueue,
+		SendQ:      si.TxQueue,
+		Local:      si.LocalAddr.String(),
+		Remote:     si.RemoteAddr.String(),
+		UID:        si.UID,
+		Inode:      si.Inode,
+		Cookie:     strconv.FormatUint(si.SK, 16),
+		Shutdown:   si.Shutdown,
+		TOS:        si.TOS,
+		TClass:     si.TClass,
+		Mark:       si.Mark,
+		CgroupID:   si.CgroupID,
+		Cong:       string(si.CONG),
+		TCPInfo:    si.TCPInfo,
+		VegasInfo:  si.VegasInfo,
+		BBRInfo:    si.BBRInfo,
+		DCTCPInfo:  si.DCTCPInfo,
+		Owners:     si.Owners,
+		Pending:    si.UnixIcons,
+		PeerOwners: si.PeerOwners,
+	}
+	if si.UnixVFS != nil {
+		r.VFSDev = si.UnixVFS.Dev
+		r.VFSInode = si.UnixVFS.Ino
+	}
+	if si.Timer != 0 && si.Timer < len(TimerState) {
+		r.Timer = &TimerRecord{
+			Name:       TimerState[si.Timer],
+			ExpiresSec: si.Timeout,
+			Retransmit: si.Retransmit,
+			Probes:     si.Probes,
+		}
+	}
+	switch {
+	case len(si.Meminfo) >= SK_MEMINFO_DROPS:
+		r.Meminfo = &MeminfoRecord{
+			RmemAlloc:  si.Meminfo[SK_MEMINFO_RMEM_ALLOC],
+			Rcvbuf:     si.Meminfo[SK_MEMINFO_RCVBUF],
+			WmemAlloc:  si.Meminfo[SK_MEMINFO_WMEM_ALLOC],
+			Sndbuf:     si.Meminfo[SK_MEMINFO_SNDBUF],
+			FwdAlloc:   si.Meminfo[SK_MEMINFO_FWD_ALLOC],
+			WmemQueued: si.Meminfo[SK_MEMINFO_WMEM_QUEUED],
+			Optmem:     si.Meminfo[SK_MEMINFO_OPTMEM],
+			Backlog:    si.Meminfo[SK_MEMINFO_BACKLOG],
+		}
+		if len(si.Meminfo) > SK_MEMINFO_DROPS {
+			r.Meminfo.Drops = si.Meminfo[SK_MEMINFO_DROPS]
+		}
+	case si.InetMeminfo != nil:
+		r.Meminfo = &MeminfoRecord{
+			RmemAlloc:  si.InetMeminfo.IdiagRmem,
+			WmemQueued: si.InetMeminfo.IdiagWmem,
+			FwdAlloc:   si.InetMeminfo.IdiagFmem,
+			WmemAlloc:  si.InetMeminfo.IdiagTmem,
+		}
+	}
+	return r
+}
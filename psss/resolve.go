@@ -0,0 +1,201 @@
+package psss
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultServicesPath        = "/etc/services"
+	DefaultResolveTimeout      = 2 * time.Second
+	DefaultResolveConcurrency  = 16
+	DefaultResolveCacheEntries = 4096
+)
+
+// Resolver turns ports into service names and addresses into host names.
+// Both sources can be replaced, Services by ParseServices on any reader and
+// LookupAddr by a stub, so that the output does not depend on the system.
+type Resolver struct {
+	Services    map[string]string // "port/proto" to name
+	LookupAddr  func(ctx context.Context, addr string) ([]string, error)
+	Timeout     time.Duration // per reverse lookup
+	Concurrency int           // reverse lookups running at the same time
+	CacheSize   int           // host names kept, failed lookups included
+
+	mu    sync.Mutex
+	hosts map[netip.Addr]string
+	order []netip.Addr // insertion order for eviction
+}
+
+func NewResolver() *Resolver {
+	return &Resolver{
+		Services:    make(map[string]string),
+		LookupAddr:  net.DefaultResolver.LookupAddr,
+		Timeout:     DefaultResolveTimeout,
+		Concurrency: DefaultResolveConcurrency,
+		CacheSize:   DefaultResolveCacheEntries,
+		hosts:       make(map[netip.Addr]string),
+	}
+}
+
+// LoadServices reads a services(5) file, DefaultServicesPath if path is empty.
+func (r *Resolver) LoadServices(path string) error {
+	if len(path) == 0 {
+		path = DefaultServicesPath
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return r.ParseServices(file)
+}
+
+// ParseServices adds the entries of a services(5) file, the first name of a
+// port wins like in getservbyport(3).
+func (r *Resolver) ParseServices(reader io.Reader) error {
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		// name port/proto [aliases...]
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		portProto := strings.SplitN(fields[1], "/", 2)
+		if len(portProto) != 2 {
+			continue
+		}
+		if _, err := strconv.ParseUint(portProto[0], 10, 16); err != nil {
+			continue
+		}
+		if _, ok := r.Services[fields[1]]; !ok {
+			r.Services[fields[1]] = fields[0]
+		}
+	}
+	return scanner.Err()
+}
+
+// ServiceProto is the services(5) protocol name used for a protocal.
+func ServiceProto(protocal int) string {
+	switch protocal {
+	case ProtocalTCP, ProtocalMPTCP:
+		return "tcp"
+	case ProtocalUDP, ProtocalUDPLite:
+		return "udp"
+	case ProtocalSCTP:
+		return "sctp"
+	case ProtocalDCCP:
+		return "dccp"
+	}
+	return ""
+}
+
+// Service returns the name of port, or an empty string when it has none.
+func (r *Resolver) Service(port uint16, proto string) string {
+	if r == nil || len(proto) == 0 {
+		return ""
+	}
+	return r.Services[strconv.FormatUint(uint64(port), 10)+"/"+proto]
+}
+
+// Host returns the cached name of addr. Addresses are only looked up by
+// Prefetch, so that printing never waits for DNS.
+func (r *Resolver) Host(addr netip.Addr) string {
+	if r == nil {
+		return ""
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.hosts[addr]
+}
+
+// Prefetch looks up the addresses which are not cached yet, at most
+// Concurrency at a time and each one for at most Timeout.
+func (r *Resolver) Prefetch(addrs []netip.Addr) {
+	var (
+		wg      sync.WaitGroup
+		pending = make(map[netip.Addr]bool)
+	)
+	workers := r.Concurrency
+	if workers <= 0 {
+		workers = 1
+	}
+	sem := make(chan struct{}, workers)
+	r.mu.Lock()
+	for _, addr := range addrs {
+		if _, ok := r.hosts[addr]; ok || !addr.IsValid() || addr.IsUnspecified() {
+			continue
+		}
+		pending[addr] = true
+	}
+	r.mu.Unlock()
+	for addr := range pending {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(addr netip.Addr) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			r.store(addr, r.lookup(addr))
+		}(addr)
+	}
+	wg.Wait()
+}
+
+func (r *Resolver) lookup(addr netip.Addr) string {
+	ctx := context.Background()
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+	names, err := r.LookupAddr(ctx, addr.Unmap().String())
+	if err != nil || len(names) == 0 {
+		return ""
+	}
+	return strings.TrimSuffix(names[0], ".")
+}
+
+// store caches name, an empty one records a failed lookup. The oldest entry
+// is dropped once CacheSize is reached.
+func (r *Resolver) store(addr netip.Addr, name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.hosts[addr]; !ok {
+		if r.CacheSize > 0 && len(r.order) >= r.CacheSize {
+			delete(r.hosts, r.order[0])
+			r.order = r.order[1:]
+		}
+		r.order = append(r.order, addr)
+	}
+	r.hosts[addr] = name
+}
+
+// AddrString is SockAddr.String with the host and port replaced by their
+// names where known. Either part stays numeric when hosts or services is off.
+func (r *Resolver) AddrString(a SockAddr, protocal int, hosts, services bool) string {
+	if r == nil || !a.IsInet() {
+		return a.String()
+	}
+	var host, port string
+	if hosts {
+		host = r.Host(a.Addr())
+	}
+	if services {
+		port = r.Service(a.Port(), ServiceProto(protocal))
+	}
+	return a.inetString(host, port)
+}
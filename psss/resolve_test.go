@@ -0,0 +1,151 @@
+package psss
+
+import (
+	"context"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseServices(t *testing.T) {
+	r := NewResolver()
+	if err := r.LoadServices("testdata/services"); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		port  uint16
+		proto string
+		want  string
+	}{
+		{22, "tcp", "ssh"},
+		{80, "tcp", "http"}, // the first name of a port wins
+		{53, "udp", "domain"},
+		{22, "udp", ""},
+		{8080, "tcp", ""},
+	} {
+		if got := r.Service(tt.port, tt.proto); got != tt.want {
+			t.Errorf("Service(%d, %s) = %q, want %q", tt.port, tt.proto, got, tt.want)
+		}
+	}
+	if len(r.Services) != 4 {
+		t.Errorf("got %d services, want 4: %v", len(r.Services), r.Services)
+	}
+}
+
+// countingLookup answers every address with its own string and counts the
+// lookups per address.
+func countingLookup(mu *sync.Mutex, calls map[string]int) func(context.Context, string) ([]string, error) {
+	return func(ctx context.Context, addr string) ([]string, error) {
+		mu.Lock()
+		calls[addr]++
+		mu.Unlock()
+		return []string{"host-" + addr + "."}, nil
+	}
+}
+
+func TestResolverCache(t *testing.T) {
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	r := NewResolver()
+	r.LookupAddr = countingLookup(&mu, calls)
+	r.CacheSize = 2
+
+	a := netip.MustParseAddr("192.0.2.1")
+	b := netip.MustParseAddr("192.0.2.2")
+	c := netip.MustParseAddr("192.0.2.3")
+
+	// one at a time, Prefetch looks up in no particular order
+	r.Prefetch([]netip.Addr{a})
+	r.Prefetch([]netip.Addr{b})
+	r.Prefetch([]netip.Addr{a, b})
+	if calls["192.0.2.1"] != 1 || calls["192.0.2.2"] != 1 {
+		t.Fatalf("cached addresses looked up again: %v", calls)
+	}
+	if got := r.Host(a); got != "host-192.0.2.1" {
+		t.Errorf("Host(%s) = %q, want host-192.0.2.1", a, got)
+	}
+
+	// the cache is full, c pushes out a, the oldest entry
+	r.Prefetch([]netip.Addr{c})
+	if got := r.Host(a); got != "" {
+		t.Errorf("Host(%s) = %q after eviction, want empty", a, got)
+	}
+	if got := r.Host(c); got != "host-192.0.2.3" {
+		t.Errorf("Host(%s) = %q, want host-192.0.2.3", c, got)
+	}
+	r.Prefetch([]netip.Addr{a, b, c})
+	if calls["192.0.2.1"] != 2 || calls["192.0.2.2"] != 1 || calls["192.0.2.3"] != 1 {
+		t.Errorf("got lookups %v, want only the evicted address again", calls)
+	}
+	if len(r.hosts) != 2 || len(r.order) != 2 {
+		t.Errorf("cache holds %d hosts in %d orders, want 2", len(r.hosts), len(r.order))
+	}
+}
+
+func TestResolverTimeout(t *testing.T) {
+	var calls int32
+	r := NewResolver()
+	r.Timeout = 10 * time.Millisecond
+	r.LookupAddr = func(ctx context.Context, addr string) ([]string, error) {
+		atomic.AddInt32(&calls, 1)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	addr := netip.MustParseAddr("2001:db8::1")
+
+	done := make(chan struct{})
+	go func() {
+		r.Prefetch([]netip.Addr{addr})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Prefetch did not return after the timeout")
+	}
+	if got := r.Host(addr); got != "" {
+		t.Errorf("Host(%s) = %q, want empty", addr, got)
+	}
+	// the failure is cached as well
+	r.Prefetch([]netip.Addr{addr})
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("got %d lookups, want 1", n)
+	}
+}
+
+func TestResolverConcurrency(t *testing.T) {
+	const limit = 3
+	var running, peak int32
+	r := NewResolver()
+	r.Concurrency = limit
+	r.LookupAddr = func(ctx context.Context, addr string) ([]string, error) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			old := atomic.LoadInt32(&peak)
+			if n <= old || atomic.CompareAndSwapInt32(&peak, old, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return []string{"host"}, nil
+	}
+	var addrs []netip.Addr
+	for i := 1; i <= 4*limit; i++ {
+		addrs = append(addrs, netip.AddrFrom4([4]byte{198, 51, 100, byte(i)}))
+	}
+	r.Prefetch(addrs)
+	if peak > limit {
+		t.Errorf("%d lookups ran at the same time, want at most %d", peak, limit)
+	}
+	if peak < 2 {
+		t.Errorf("at most %d lookup ran at a time, want them in parallel", peak)
+	}
+	for _, addr := range addrs {
+		if r.Host(addr) != "host" {
+			t.Errorf("Host(%s) not cached", addr)
+		}
+	}
+}
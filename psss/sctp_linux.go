@@ -0,0 +1,315 @@
+// +build linux
+
+package psss
+
+import (
+	"bufio"
+	"net/netip"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	SCTP_STATE_CLOSED = iota
+	SCTP_STATE_COOKIE_WAIT
+	SCTP_STATE_COOKIE_ECHOED
+	SCTP_STATE_ESTABLISHED
+	SCTP_STATE_SHUTDOWN_PENDING
+	SCTP_STATE_SHUTDOWN_SENT
+	SCTP_STATE_SHUTDOWN_RECEIVED
+	SCTP_STATE_SHUTDOWN_ACK_SENT
+	SCTP_STATE_MAX
+)
+
+const (
+	SizeOfSockaddrStorage = 128
+	SizeOfSCTPInfo        = 368
+)
+
+var (
+	SCTPSstate = []string{
+		"CLOSED",
+		"COOKIE_WAIT",
+		"COOKIE_ECHOED",
+		"ESTAB",
+		"SHUTDOWN_PENDING",
+		"SHUTDOWN_SENT",
+		"SHUTDOWN_RECV",
+		"SHUTDOWN_ACK_SENT",
+	}
+
+	// SCTPStatus maps the state of an association to the closest generic state,
+	// so that state filters work the same for every protocal.
+	SCTPStatus = []uint8{
+		SsUNCONN,
+		SsSYNSENT,
+		SsSYNSENT,
+		SsESTAB,
+		SsFINWAIT1,
+		SsFINWAIT1,
+		SsCLOSEWAIT,
+		SsLASTACK,
+	}
+)
+
+type SCTPInfo struct {
+	Tag                uint32
+	State              uint32
+	Rwnd               uint32
+	Unackdata          uint16
+	Penddata           uint16
+	Instrms            uint16
+	Outstrms           uint16
+	FragmentationPoint uint32
+	Inqueue            uint32
+	Outqueue           uint32
+	OverallError       uint32
+	MaxBurst           uint32
+	Maxseg             uint32
+	PeerRwnd           uint32
+	PeerTag            uint32
+	PeerCapable        uint8
+	PeerSack           uint8
+	Reserved1          uint16
+	// assoc status info
+	Isacks       uint64
+	Osacks       uint64
+	Opackets     uint64
+	Ipackets     uint64
+	Rtxchunks    uint64
+	Outofseqtsns uint64
+	Idupchunks   uint64
+	Gapcnt       uint64
+	Ouodchunks   uint64
+	Iuodchunks   uint64
+	Oodchunks    uint64
+	Iodchunks    uint64
+	Octrlchunks  uint64
+	Ictrlchunks  uint64
+	// primary transport info
+	PAddress           [SizeOfSockaddrStorage]byte
+	PState             int32
+	PCwnd              uint32
+	PSrtt              uint32
+	PRto               uint32
+	PHbinterval        uint32
+	PPathmaxrxt        uint32
+	PSackdelay         uint32
+	PSackfreq          uint32
+	PSsthresh          uint32
+	PPartialBytesAcked uint32
+	PFlightSize        uint32
+	PError             uint16
+	Reserved2          uint16
+	// sctp sock info
+	SAutoclose        uint32
+	SAdaptationInd    uint32
+	SPdPoint          uint32
+	SNodelay          uint8
+	SDisableFragments uint8
+	SV4mapped         uint8
+	SFragInterleave   uint8
+	SType             uint32
+	Reserved3         uint32
+}
+
+// parseSockaddrStorage decodes a struct sockaddr_storage as used by
+// INET_DIAG_LOCALS and INET_DIAG_PEERS.
+func parseSockaddrStorage(raw []byte) (sa SockAddr, ok bool) {
+	if len(raw) < unix.SizeofSockaddrInet6 {
+		return sa, false
+	}
+	port := uint16(raw[2])<<8 | uint16(raw[3])
+	switch *(*uint16)(unsafe.Pointer(&raw[0])) {
+	case unix.AF_INET:
+		sa.AddrPort = netip.AddrPortFrom(netip.AddrFrom4([4]byte{raw[4], raw[5], raw[6], raw[7]}), port)
+	case unix.AF_INET6:
+		var addr [16]byte
+		copy(addr[:], raw[8:24])
+		sa.AddrPort = netip.AddrPortFrom(netip.AddrFrom16(addr), port)
+		sa.IfIndex = *(*uint32)(unsafe.Pointer(&raw[24]))
+	default:
+		return sa, false
+	}
+	return sa, true
+}
+
+func parseSockaddrList(raw []byte) (sas []SockAddr) {
+	for i := 0; i+SizeOfSockaddrStorage <= len(raw); i += SizeOfSockaddrStorage {
+		if sa, ok := parseSockaddrStorage(raw[i : i+SizeOfSockaddrStorage]); ok {
+			sas = append(sas, sa)
+		}
+	}
+	return sas
+}
+
+// fixSCTPState turns the SCTP state of an association into a generic one.
+// Endpoints already use the generic LISTEN and CLOSE states.
+func (si *SocketInfo) fixSCTPState() {
+	if len(si.RemoteAddrs) == 0 {
+		return
+	}
+	si.SCTPAssoc = true
+	si.SCTPState = si.Status
+	if int(si.Status) < len(SCTPStatus) {
+		si.Status = SCTPStatus[si.Status]
+	} else {
+		si.Status = SsUNKNOWN
+	}
+	si.RemoteAddr = si.RemoteAddrs[0]
+}
+
+// parseProcSCTPAddrs reads the address list of /proc/net/sctp, where the
+// primary peer address is marked with '*'.
+func parseProcSCTPAddrs(fields []string, port uint16) (sas []SockAddr, primary int) {
+	for i, field := range fields {
+		if strings.HasPrefix(field, "*") {
+			primary = i
+			field = field[1:]
+		}
+		addr, err := netip.ParseAddr(field)
+		if err != nil {
+			continue
+		}
+		sas = append(sas, SockAddr{AddrPort: netip.AddrPortFrom(addr, port)})
+	}
+	return sas, primary
+}
+
+func sctpAddrsMatchAf(sas []SockAddr, af int) bool {
+	if len(sas) == 0 {
+		return true
+	}
+	return sas[0].Addr().Is4() == (af == unix.AF_INET)
+}
+
+// sctpReadProc reads the endpoints from /proc/net/sctp/eps and the
+// associations from /proc/net/sctp/assocs.
+func (c *Client) sctpReadProc(af int, fn func(si *SocketInfo) bool) (err error) {
+	var (
+		fields    []string
+		tempInt64 int64
+		tempUint  uint64
+		end       int
+	)
+	emit := func(si *SocketInfo) bool {
+		if !c.match(si) {
+			return true
+		}
+		if c.Process {
+			si.SetUpRelation()
+		}
+		return fn(si)
+	}
+
+	file, err := c.openProc("SCTPEps")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// ENDPT SOCK STY SST HBKT LPORT UID INODE LADDRS
+		fields = strings.Fields(scanner.Text())
+		if len(fields) < 9 || fields[0] == "ENDPT" {
+			continue
+		}
+		si := NewSocketInfo()
+		si.Protocal = ProtocalSCTP
+		if si.SK, err = strconv.ParseUint(fields[1], 16, 64); err != nil {
+			continue
+		}
+		if tempInt64, err = strconv.ParseInt(fields[3], 10, 32); err != nil {
+			continue
+		}
+		si.Status = uint8(tempInt64)
+		if tempUint, err = strconv.ParseUint(fields[5], 10, 16); err != nil {
+			continue
+		}
+		if si.UID, err = strconv.ParseUint(fields[6], 10, 64); err != nil {
+			continue
+		}
+		if tempInt64, err = strconv.ParseInt(fields[7], 10, 64); err != nil {
+			continue
+		}
+		si.Inode = uint32(tempInt64)
+		si.LocalAddrs, _ = parseProcSCTPAddrs(fields[8:], uint16(tempUint))
+		if len(si.LocalAddrs) == 0 || !sctpAddrsMatchAf(si.LocalAddrs, af) {
+			continue
+		}
+		si.LocalAddr = si.LocalAddrs[0]
+		if !emit(si) {
+			return nil
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+
+	assocs, err := c.openProc("SCTPAssocs")
+	if err != nil {
+		return err
+	}
+	defer assocs.Close()
+	scanner = bufio.NewScanner(assocs)
+	for scanner.Scan() {
+		// ASSOC SOCK STY SST ST HBKT ASSOC-ID TX_QUEUE RX_QUEUE UID INODE LPORT RPORT LADDRS <-> RADDRS HBINT ...
+		fields = strings.Fields(scanner.Text())
+		if len(fields) < 15 || fields[0] == "ASSOC" {
+			continue
+		}
+		si := NewSocketInfo()
+		si.Protocal = ProtocalSCTP
+		if si.SK, err = strconv.ParseUint(fields[1], 16, 64); err != nil {
+			continue
+		}
+		if tempInt64, err = strconv.ParseInt(fields[4], 10, 32); err != nil {
+			continue
+		}
+		si.Status = uint8(tempInt64)
+		if tempInt64, err = strconv.ParseInt(fields[7], 10, 64); err != nil {
+			continue
+		}
+		si.TxQueue = uint32(tempInt64)
+		if tempInt64, err = strconv.ParseInt(fields[8], 10, 64); err != nil {
+			continue
+		}
+		si.RxQueue = uint32(tempInt64)
+		if si.UID, err = strconv.ParseUint(fields[9], 10, 64); err != nil {
+			continue
+		}
+		if tempInt64, err = strconv.ParseInt(fields[10], 10, 64); err != nil {
+			continue
+		}
+		si.Inode = uint32(tempInt64)
+		if tempUint, err = strconv.ParseUint(fields[11], 10, 16); err != nil {
+			continue
+		}
+		for end = 13; end < len(fields) && fields[end] != "<->"; end++ {
+		}
+		if end == len(fields) {
+			continue
+		}
+		si.LocalAddrs, _ = parseProcSCTPAddrs(fields[13:end], uint16(tempUint))
+		if tempUint, err = strconv.ParseUint(fields[12], 10, 16); err != nil {
+			continue
+		}
+		var primary int
+		si.RemoteAddrs, primary = parseProcSCTPAddrs(fields[end+1:], uint16(tempUint))
+		if len(si.LocalAddrs) == 0 || len(si.RemoteAddrs) == 0 || !sctpAddrsMatchAf(si.LocalAddrs, af) {
+			continue
+		}
+		si.LocalAddr = si.LocalAddrs[0]
+		si.fixSCTPState()
+		if primary < len(si.RemoteAddrs) {
+			si.RemoteAddr = si.RemoteAddrs[primary]
+		}
+		if !emit(si) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
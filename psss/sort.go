@@ -0,0 +1,108 @@
+package psss
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SortKey orders sockets by one property, Reverse turns the order around.
+type SortKey struct {
+	Name    string
+	Reverse bool
+}
+
+var (
+	SortKeyCompare = map[string]func(a, b *SocketInfo) int{
+		"state":  func(a, b *SocketInfo) int { return compareUint32(uint32(a.Status), uint32(b.Status)) },
+		"local":  func(a, b *SocketInfo) int { return a.LocalAddr.Compare(b.LocalAddr) },
+		"remote": func(a, b *SocketInfo) int { return a.RemoteAddr.Compare(b.RemoteAddr) },
+		"sport": func(a, b *SocketInfo) int {
+			return compareUint32(uint32(a.LocalAddr.Port()), uint32(b.LocalAddr.Port()))
+		},
+		"dport": func(a, b *SocketInfo) int {
+			return compareUint32(uint32(a.RemoteAddr.Port()), uint32(b.RemoteAddr.Port()))
+		},
+		"recvq": func(a, b *SocketInfo) int { return compareUint32(a.RxQueue, b.RxQueue) },
+		"sendq": func(a, b *SocketInfo) int { return compareUint32(a.TxQueue, b.TxQueue) },
+		"rtt": func(a, b *SocketInfo) int {
+			var x, y uint32
+			if a.TCPInfo != nil {
+				x = a.TCPInfo.Rtt
+			}
+			if b.TCPInfo != nil {
+				y = b.TCPInfo.Rtt
+			}
+			return compareUint32(x, y)
+		},
+		"bytes_acked": func(a, b *SocketInfo) int {
+			var x, y uint64
+			if a.TCPInfo != nil {
+				x = a.TCPInfo.Bytes_acked
+			}
+			if b.TCPInfo != nil {
+				y = b.TCPInfo.Bytes_acked
+			}
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		},
+		"process": func(a, b *SocketInfo) int {
+			switch {
+			case len(a.Owners) == 0 && len(b.Owners) == 0:
+				return 0
+			case len(a.Owners) == 0:
+				return 1
+			case len(b.Owners) == 0:
+				return -1
+			}
+			if c := strings.Compare(a.Owners[0].Name, b.Owners[0].Name); c != 0 {
+				return c
+			}
+			return compareUint32(uint32(a.Owners[0].Pid), uint32(b.Owners[0].Pid))
+		},
+	}
+)
+
+// ParseSortKeys reads a comma separated list of SortKeyCompare names, each
+// one may start with '-' for a descending order.
+func ParseSortKeys(spec string) (keys []SortKey, err error) {
+	for _, field := range strings.Split(spec, ",") {
+		var key SortKey
+		field = strings.TrimSpace(field)
+		if strings.HasPrefix(field, "-") {
+			key.Reverse = true
+			field = field[1:]
+		}
+		if _, ok := SortKeyCompare[field]; !ok {
+			return nil, fmt.Errorf("invalid sort key:[%s]", field)
+		}
+		key.Name = field
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// CompareSocketInfo compares by the first key that tells a and b apart.
+func CompareSocketInfo(a, b *SocketInfo, keys []SortKey) int {
+	for _, key := range keys {
+		c := SortKeyCompare[key.Name](a, b)
+		if key.Reverse {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func SortSocketInfos(sis []SocketInfo, keys []SortKey) {
+	sort.SliceStable(sis, func(i, j int) bool {
+		return CompareSocketInfo(&sis[i], &sis[j], keys) < 0
+	})
+}
@@ -2,9 +2,15 @@ package psss
 
 import (
 	"fmt"
+	"io"
 	"math"
+	"net"
+	"net/netip"
 	"strconv"
 	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
 )
 
 const (
@@ -46,11 +52,6 @@ const (
 	SK_MEMINFO_VARS
 )
 
-const (
-	IPv4String = "IPv4"
-	IPv6String = "IPv6"
-)
-
 var (
 	Sstate = []string{
 		"UNKNOWN",
@@ -86,75 +87,137 @@ var (
 		"PERSIST",
 		"UNKNOWN",
 	}
-
-	SummaryPF = []string{
-		"TCP",
-		"UDP",
-		"UDPLITE",
-		"RAW",
-		"FRAG",
-	}
-
-	Colons = []string{
-		":::::::",
-		"::::::",
-		":::::",
-		"::::",
-		":::",
-	}
 )
 
-type IP struct {
-	Host string
-	Port string
+// SockAddr is one end of a socket. Inet sockets fill AddrPort and the
+// interface the socket is bound to, other families fill Name and ID, e.g.
+// the path and inode of a unix socket.
+type SockAddr struct {
+	netip.AddrPort
+	IfIndex uint32
+	Name    string
+	ID      uint32
 }
 
-func (i IP) String() (str string) {
-	return i.Host + ":" + i.Port
+func (a SockAddr) IsInet() bool {
+	return a.AddrPort.IsValid()
 }
 
-func IPv4HexToString(ipHex string) (ip string, err error) {
-	if len(ipHex) != 8 {
-		return ip, fmt.Errorf("invalid input:[%s]", ipHex)
+func (a SockAddr) String() (str string) {
+	if !a.IsInet() {
+		if len(a.Name) == 0 {
+			str = "*"
+		} else {
+			str = a.Name
+		}
+		switch {
+		case a.IfIndex != 0:
+			str += ":" + ifIndexName(a.IfIndex)
+		case a.ID != 0:
+			str += ":" + strconv.FormatUint(uint64(a.ID), 10)
+		}
+		return str
 	}
-	var tempInt64 int64
-	for i := 3; i > 0; i-- {
-		if tempInt64, err = strconv.ParseInt(ipHex[i*2:(i+1)*2], 16, 64); err != nil {
-			return "", err
+	return a.inetString("", "")
+}
+
+// inetString joins host and port, either one falls back to its numeric form
+// when empty.
+func (a SockAddr) inetString(host, port string) (str string) {
+	if len(host) == 0 {
+		addr := a.Addr()
+		str = addr.WithZone("").String()
+		if a.IfIndex != 0 {
+			str += "%" + ifIndexName(a.IfIndex)
+		}
+		if addr.Is6() {
+			str = "[" + str + "]"
 		}
-		ip += fmt.Sprintf("%d", tempInt64) + "."
+	} else {
+		str = host
 	}
-	if tempInt64, err = strconv.ParseInt(ipHex[0:2], 16, 64); err != nil {
-		return "", err
+	if len(port) == 0 {
+		port = strconv.FormatUint(uint64(a.Port()), 10)
+	}
+	return str + ":" + port
+}
+
+func ifIndexName(index uint32) string {
+	if ifi, err := net.InterfaceByIndex(int(index)); err == nil {
+		return ifi.Name
 	}
-	ip += fmt.Sprintf("%d", tempInt64)
-	return ip, nil
+	return strconv.FormatUint(uint64(index), 10)
 }
 
-func IPv6HexToString(ipHex string) (ip string, err error) {
-	prefix := ipHex[:24]
-	suffix := ipHex[24:]
-	for i := 0; i < 6; i++ {
-		if prefix[i:i+4] == "0000" {
-			ip += ":"
-			continue
+func (a SockAddr) Compare(b SockAddr) int {
+	switch {
+	case a.IsInet() && !b.IsInet():
+		return -1
+	case !a.IsInet() && b.IsInet():
+		return 1
+	case !a.IsInet():
+		if c := strings.Compare(a.Name, b.Name); c != 0 {
+			return c
 		}
-		ip += prefix[i:i+4] + ":"
+		return compareUint32(a.ID, b.ID)
 	}
-	for i := range Colons {
-		ip = strings.Replace(ip, Colons[i], "::", -1)
+	if c := a.AddrPort.Compare(b.AddrPort); c != 0 {
+		return c
 	}
-	if suffix, err = IPv4HexToString(suffix); err != nil {
-		return "", err
+	return compareUint32(a.IfIndex, b.IfIndex)
+}
+
+func compareUint32(a, b uint32) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// ParseProcHexAddr decodes an address of /proc/net/tcp and friends, which the
+// kernel prints as 32 bits words in host byte order.
+func ParseProcHexAddr(ipHex string) (addr netip.Addr, err error) {
+	if len(ipHex) != 8 && len(ipHex) != 32 {
+		return addr, fmt.Errorf("invalid input:[%s]", ipHex)
 	}
-	ip += suffix
-	return ip, nil
+	var (
+		raw  [16]byte
+		word uint64
+	)
+	for i := 0; i < len(ipHex)/8; i++ {
+		if word, err = strconv.ParseUint(ipHex[i*8:(i+1)*8], 16, 32); err != nil {
+			return addr, err
+		}
+		*(*uint32)(unsafe.Pointer(&raw[i*4])) = uint32(word)
+	}
+	if len(ipHex) == 8 {
+		return netip.AddrFrom4([4]byte{raw[0], raw[1], raw[2], raw[3]}), nil
+	}
+	return netip.AddrFrom16(raw), nil
+}
+
+const (
+	SourceNone = iota
+	SourceNetlink
+	SourceProc
+)
+
+var SourceName = []string{
+	"",
+	"netlink",
+	"proc",
 }
 
 type SocketInfo struct {
 	// Generic
-	LocalAddr  IP
-	RemoteAddr IP
+	Protocal   int    // Protocal* the socket was read as
+	NetNS      string // namespace name when read from another namespace
+	Source     int    // Source* the socket was read from
+	LocalAddr  SockAddr
+	RemoteAddr SockAddr
 	Status     uint8
 	TxQueue    uint32
 	RxQueue    uint32
@@ -178,13 +241,50 @@ type SocketInfo struct {
 	VegasInfo *TCPVegasInfo
 	CONG      []byte
 	// Extended Info
-	Drops   int   // Generic like UDP, RAW specific
-	Type    uint8 // socket type
-	Meminfo []uint32
-	// Related processes
-	UserName string
-	// Flag
-	IsEnd bool
+	Drops       int   // Generic like UDP, RAW specific
+	Type        uint8 // socket type
+	Meminfo     []uint32
+	InetMeminfo *InetDiagMeminfo
+	TOS         uint8
+	TClass      uint8
+	ClassID     uint32
+	Shutdown    uint8 // bit 0 for receive, bit 1 for send
+	V6Only      *bool // only reported for IPv6 sockets
+	Mark        uint32
+	CgroupID    uint64
+	SockOpt     uint16 // INET_DIAG_SOCKOPT_* bits
+	DCTCPInfo   *TCPDCTCPInfo
+	BBRInfo     *TCPBBRInfo
+	MD5Sig      []TCPDiagMD5Sig
+	ULPName     string
+	TLSInfo     *TLSInfo
+	// SCTP specific
+	SCTPAssoc   bool       // an association rather than an endpoint
+	SCTPState   uint8      // SCTP_STATE_* of an association
+	LocalAddrs  []SockAddr // all addresses of a multi-homed socket
+	RemoteAddrs []SockAddr
+	SCTPInfo    *SCTPInfo
+	// Packet specific
+	PacketProtocol uint16 // ETH_P_* in host byte order
+	PacketInfo     *PacketDiagInfo
+	PacketMclist   []PacketDiagMclist
+	PacketRxRing   *PacketDiagRing
+	PacketTxRing   *PacketDiagRing
+	PacketFanout   *uint32 // group id in the low 16 bits, type in the high ones
+	PacketFilter   []unix.SockFilter
+	// MPTCP specific
+	MPTCPInfo *MPTCPInfo
+	// Netlink specific
+	NetlinkProtocol uint8
+	NetlinkGroups   []uint32
+	NetlinkFlags    uint32
+	// Unix specific
+	UnixName   UnixName      // LocalAddr.Name is its escaped form
+	UnixVFS    *UnixDiagVFS  // device and inode of the bound file
+	UnixIcons  []uint32      // inodes of connections waiting to be accepted
+	PeerOwners []SocketOwner // processes holding the peer
+	// Related processes, shared with GlobalSocketOwners
+	Owners []SocketOwner
 }
 
 func NewSocketInfo() *SocketInfo {
@@ -193,10 +293,11 @@ func NewSocketInfo() *SocketInfo {
 }
 
 func (si *SocketInfo) Reset() {
-	si.LocalAddr.Host = ""
-	si.LocalAddr.Port = ""
-	si.RemoteAddr.Host = ""
-	si.RemoteAddr.Port = ""
+	si.Protocal = 0
+	si.NetNS = ""
+	si.Source = SourceNone
+	si.LocalAddr = SockAddr{}
+	si.RemoteAddr = SockAddr{}
 	si.Status = 0
 	si.TxQueue = 0
 	si.RxQueue = 0
@@ -220,70 +321,163 @@ func (si *SocketInfo) Reset() {
 	si.Drops = 0
 	si.Type = 0
 	si.Meminfo = nil
-	si.UserName = ""
-	si.IsEnd = false
+	si.InetMeminfo = nil
+	si.TOS = 0
+	si.TClass = 0
+	si.ClassID = 0
+	si.Shutdown = 0
+	si.V6Only = nil
+	si.Mark = 0
+	si.CgroupID = 0
+	si.SockOpt = 0
+	si.DCTCPInfo = nil
+	si.BBRInfo = nil
+	si.MD5Sig = nil
+	si.ULPName = ""
+	si.TLSInfo = nil
+	si.SCTPAssoc = false
+	si.SCTPState = 0
+	si.LocalAddrs = nil
+	si.RemoteAddrs = nil
+	si.SCTPInfo = nil
+	si.PacketProtocol = 0
+	si.PacketInfo = nil
+	si.PacketMclist = nil
+	si.PacketRxRing = nil
+	si.PacketTxRing = nil
+	si.PacketFanout = nil
+	si.PacketFilter = nil
+	si.MPTCPInfo = nil
+	si.NetlinkProtocol = 0
+	si.NetlinkGroups = nil
+	si.NetlinkFlags = 0
+	si.UnixName = UnixName{}
+	si.UnixVFS = nil
+	si.UnixIcons = nil
+	si.PeerOwners = nil
+	si.Owners = nil
 }
 
 func (si *SocketInfo) SetUpRelation() {
-	var (
-		map2L map[uint32]Fd
-		ok    bool
-	)
-	for name, map1L := range GlobalProcFds {
-		for _, map2L = range map1L {
-			if _, ok = map2L[si.Inode]; ok {
-				si.UserName = name
-				return
-			}
-		}
-	}
+	si.Owners = GlobalSocketOwners[si.Inode]
 }
 
-func (si *SocketInfo) GenericInfoPrint() {
-	if len(Sstate[si.Status]) >= 8 {
-		fmt.Printf("%s\t", Sstate[si.Status])
-	} else {
-		fmt.Printf("%s\t\t", Sstate[si.Status])
+func (si *SocketInfo) StateString() string {
+	if si.SCTPAssoc && int(si.SCTPState) < len(SCTPSstate) {
+		return "`- " + SCTPSstate[si.SCTPState]
+	}
+	if int(si.Status) < len(Sstate) {
+		return Sstate[si.Status]
 	}
-	fmt.Printf("%d\t%d\t%-*s\t%-*s\t", si.RxQueue, si.TxQueue, MaxLocalAddrLength, si.LocalAddr.String(), MaxRemoteAddrLength, si.RemoteAddr.String())
+	return Sstate[SsUNKNOWN]
 }
 
-func (si *SocketInfo) ProcInfoPrint() {
-	var inode uint32
-	fmt.Printf(`["%s":`, si.UserName)
-	for pid, map1L := range GlobalProcFds[si.UserName] {
-		for inode = range map1L {
-			if si.Inode == inode {
-				fmt.Printf(`(pid=%d,fd=%s)`, pid, map1L[inode].Name)
-			}
+func ownersString(prefix string, owners []SocketOwner) string {
+	var b strings.Builder
+	b.WriteString(prefix)
+	b.WriteString(":(")
+	for i := range owners {
+		if i > 0 {
+			b.WriteString(",")
 		}
+		fmt.Fprintf(&b, `("%s",pid=%d,fd=%d)`, owners[i].Name, owners[i].Pid, owners[i].Fd)
 	}
-	fmt.Printf("]")
+	b.WriteString(")")
+	return b.String()
+}
+
+func (si *SocketInfo) OwnersString() string {
+	return ownersString("users", si.Owners)
 }
 
-func (si *SocketInfo) TimerInfoPrint() {
-	fmt.Printf("[timer:(%s,%dsec,", TimerState[si.Timer], si.Timeout)
+// PeerOwnersString is OwnersString for the peer of a unix socket.
+func (si *SocketInfo) PeerOwnersString() string {
+	return ownersString("peer_users", si.PeerOwners)
+}
+
+func (si *SocketInfo) ProcInfoPrint(w io.Writer) {
+	fmt.Fprintf(w, "%s", si.OwnersString())
+}
+
+func (si *SocketInfo) TimerInfoPrint(w io.Writer) {
+	fmt.Fprintf(w, "[timer:(%s,%dsec,", TimerState[si.Timer], si.Timeout)
 	if si.Timer != 1 {
-		fmt.Printf("%d)]    ", si.Probes)
+		fmt.Fprintf(w, "%d)]    ", si.Probes)
 	} else {
-		fmt.Printf("%d)]    ", si.Retransmit)
+		fmt.Fprintf(w, "%d)]    ", si.Retransmit)
 	}
 }
 
-func (si *SocketInfo) ExtendInfoPrint() {
-	fmt.Printf("[detail:(")
+func (si *SocketInfo) ExtendInfoPrint(w io.Writer) {
+	fmt.Fprintf(w, "[detail:(")
 	if si.UID != 0 {
-		fmt.Printf("uid:%d,", si.UID)
+		fmt.Fprintf(w, "uid:%d,", si.UID)
 	}
-	fmt.Printf("ino:%d,sk:%x", si.Inode, si.SK)
+	fmt.Fprintf(w, "ino:%d,sk:%x", si.Inode, si.SK)
 	if len(si.Opt) > 0 {
-		fmt.Printf(",opt:%v", si.Opt)
+		fmt.Fprintf(w, ",opt:%v", si.Opt)
+	}
+	if si.Shutdown != 0 {
+		fmt.Fprintf(w, ",shutdown:")
+		if si.Shutdown&1 != 0 {
+			fmt.Fprintf(w, "-")
+		} else {
+			fmt.Fprintf(w, "<")
+		}
+		fmt.Fprintf(w, "-")
+		if si.Shutdown&2 != 0 {
+			fmt.Fprintf(w, "-")
+		} else {
+			fmt.Fprintf(w, ">")
+		}
+	}
+	if si.V6Only != nil {
+		if *si.V6Only {
+			fmt.Fprintf(w, ",v6only:1")
+		} else {
+			fmt.Fprintf(w, ",v6only:0")
+		}
+	}
+	if si.TOS != 0 {
+		fmt.Fprintf(w, ",tos:0x%x", si.TOS)
+	}
+	if si.TClass != 0 {
+		fmt.Fprintf(w, ",tclass:0x%x", si.TClass)
 	}
-	fmt.Printf(")]    ")
+	if si.ClassID != 0 {
+		fmt.Fprintf(w, ",class_id:0x%x", si.ClassID)
+	}
+	if si.Mark != 0 {
+		fmt.Fprintf(w, ",fwmark:0x%x", si.Mark)
+	}
+	if si.CgroupID != 0 {
+		fmt.Fprintf(w, ",cgroup:%d", si.CgroupID)
+	}
+	if si.SockOpt != 0 {
+		fmt.Fprintf(w, ",sockopt:")
+		sep := ""
+		for i := range SockOptName {
+			if si.SockOpt&(1<<i) != 0 {
+				fmt.Fprintf(w, "%s%s", sep, SockOptName[i])
+				sep = "|"
+			}
+		}
+	}
+	fmt.Fprintf(w, ")]    ")
 }
 
-func (si *SocketInfo) MeminfoPrint() {
-	fmt.Printf("[skmem:(r:%d,rb:%d,t:%d,tb:%d,f:%d,w:%d,o:%d,bl:%d)]    ",
+func (si *SocketInfo) MeminfoPrint(w io.Writer) {
+	if len(si.Meminfo) < SK_MEMINFO_DROPS {
+		if si.InetMeminfo != nil {
+			fmt.Fprintf(w, "[mem:(r:%d,w:%d,f:%d,t:%d)]    ",
+				si.InetMeminfo.IdiagRmem,
+				si.InetMeminfo.IdiagWmem,
+				si.InetMeminfo.IdiagFmem,
+				si.InetMeminfo.IdiagTmem)
+		}
+		return
+	}
+	fmt.Fprintf(w, "[skmem:(r:%d,rb:%d,t:%d,tb:%d,f:%d,w:%d,o:%d,bl:%d",
 		si.Meminfo[SK_MEMINFO_RMEM_ALLOC],
 		si.Meminfo[SK_MEMINFO_RCVBUF],
 		si.Meminfo[SK_MEMINFO_WMEM_ALLOC],
@@ -292,84 +486,112 @@ func (si *SocketInfo) MeminfoPrint() {
 		si.Meminfo[SK_MEMINFO_WMEM_QUEUED],
 		si.Meminfo[SK_MEMINFO_OPTMEM],
 		si.Meminfo[SK_MEMINFO_BACKLOG])
+	if len(si.Meminfo) > SK_MEMINFO_DROPS {
+		fmt.Fprintf(w, ",d:%d", si.Meminfo[SK_MEMINFO_DROPS])
+	}
+	fmt.Fprintf(w, ")]    ")
 }
 
-func (si *SocketInfo) TCPInfoPrint() {
-	fmt.Printf("[internal:(")
+func (si *SocketInfo) TCPInfoPrint(w io.Writer) {
+	fmt.Fprintf(w, "[internal:(")
 	if si.TCPInfo.Options&TCPI_OPT_TIMESTAMPS != 0 {
-		fmt.Printf(" ts")
+		fmt.Fprintf(w, " ts")
 	}
 	if si.TCPInfo.Options&TCPI_OPT_SACK != 0 {
-		fmt.Printf(" sack")
+		fmt.Fprintf(w, " sack")
 	}
 	if si.TCPInfo.Options&TCPI_OPT_ECN != 0 {
-		fmt.Printf(" ecn")
+		fmt.Fprintf(w, " ecn")
 	}
 	if si.TCPInfo.Options&TCPI_OPT_ECN_SEEN != 0 {
-		fmt.Printf(" ecnseen")
+		fmt.Fprintf(w, " ecnseen")
 	}
 	if si.TCPInfo.Options&TCPI_OPT_SYN_DATA != 0 {
-		fmt.Printf(" fastopen")
+		fmt.Fprintf(w, " fastopen")
 	}
-	if len(si.CONG) > 1 {
-		fmt.Printf(" %s", string(si.CONG))
+	if len(si.CONG) > 0 {
+		fmt.Fprintf(w, " %s", string(si.CONG))
 	}
 	if si.TCPInfo.Options&TCPI_OPT_WSCALE != 0 {
-		fmt.Printf(" wscale:%d,%d", si.TCPInfo.Pad_cgo_0[0]&0xf, si.TCPInfo.Pad_cgo_0[0]>>4)
+		fmt.Fprintf(w, " wscale:%d,%d", si.TCPInfo.Snd_wscale, si.TCPInfo.Rcv_wscale)
 	}
 	if si.TCPInfo.Rto != 0 && si.TCPInfo.Rto != 3000000 {
-		fmt.Printf(" rto:%.2f", float64(si.TCPInfo.Rto)/1000)
+		fmt.Fprintf(w, " rto:%.2f", float64(si.TCPInfo.Rto)/1000)
 	}
 	if si.TCPInfo.Backoff != 0 {
-		fmt.Printf(" bakcoff:%d", si.TCPInfo.Backoff)
+		fmt.Fprintf(w, " bakcoff:%d", si.TCPInfo.Backoff)
 	}
 	if si.TCPInfo.Rtt != 0 {
-		fmt.Printf(" rtt:%.2f/%.2f", float64(si.TCPInfo.Rtt)/1000, float64(si.TCPInfo.Rttvar)/1000)
+		fmt.Fprintf(w, " rtt:%.2f/%.2f", float64(si.TCPInfo.Rtt)/1000, float64(si.TCPInfo.Rttvar)/1000)
 	}
 	if si.TCPInfo.Ato != 0 {
-		fmt.Printf(" ato:%.2f", float64(si.TCPInfo.Ato)/1000)
+		fmt.Fprintf(w, " ato:%.2f", float64(si.TCPInfo.Ato)/1000)
 	}
 	if si.QACK != 0 {
-		fmt.Printf(" qack:%d", si.QACK)
+		fmt.Fprintf(w, " qack:%d", si.QACK)
 	}
 	if si.QACK&1 != 0 {
-		fmt.Printf(" bidir")
+		fmt.Fprintf(w, " bidir")
 	}
 	if si.TCPInfo.Snd_mss != 0 {
-		fmt.Printf(" mss:%d", si.TCPInfo.Snd_mss)
+		fmt.Fprintf(w, " mss:%d", si.TCPInfo.Snd_mss)
 	}
 	if si.TCPInfo.Rcv_mss != 0 {
-		fmt.Printf(" rcvmss:%d", si.TCPInfo.Rcv_mss)
+		fmt.Fprintf(w, " rcvmss:%d", si.TCPInfo.Rcv_mss)
 	}
 	if si.TCPInfo.Advmss != 0 {
-		fmt.Printf(" advmss:%d", si.TCPInfo.Advmss)
+		fmt.Fprintf(w, " advmss:%d", si.TCPInfo.Advmss)
 	}
 	if si.TCPInfo.Snd_cwnd != 0 {
-		fmt.Printf(" cwnd:%d", si.TCPInfo.Snd_cwnd)
+		fmt.Fprintf(w, " cwnd:%d", si.TCPInfo.Snd_cwnd)
 	}
 	if si.TCPInfo.Snd_ssthresh < 0xffff {
-		fmt.Printf(" ssthresh:%d", si.TCPInfo.Snd_ssthresh)
+		fmt.Fprintf(w, " ssthresh:%d", si.TCPInfo.Snd_ssthresh)
+	}
+	if si.TCPInfo.Bytes_sent != 0 {
+		fmt.Fprintf(w, " bytes_sent:%s", BwToStr(float64(si.TCPInfo.Bytes_sent)))
+	}
+	if si.TCPInfo.Bytes_retrans != 0 {
+		fmt.Fprintf(w, " bytes_retrans:%s", BwToStr(float64(si.TCPInfo.Bytes_retrans)))
 	}
 	if si.TCPInfo.Bytes_acked != 0 {
-		fmt.Printf(" bytes_acked:%s", BwToStr(float64(si.TCPInfo.Bytes_acked)))
+		fmt.Fprintf(w, " bytes_acked:%s", BwToStr(float64(si.TCPInfo.Bytes_acked)))
 	}
 	if si.TCPInfo.Bytes_received != 0 {
-		fmt.Printf(" bytes_received:%s", BwToStr(float64(si.TCPInfo.Bytes_received)))
+		fmt.Fprintf(w, " bytes_received:%s", BwToStr(float64(si.TCPInfo.Bytes_received)))
 	}
 	if si.TCPInfo.Segs_out != 0 {
-		fmt.Printf(" segs_out:%d", si.TCPInfo.Segs_out)
+		fmt.Fprintf(w, " segs_out:%d", si.TCPInfo.Segs_out)
 	}
 	if si.TCPInfo.Segs_in != 0 {
-		fmt.Printf(" segs_in:%d", si.TCPInfo.Segs_in)
+		fmt.Fprintf(w, " segs_in:%d", si.TCPInfo.Segs_in)
 	}
 	if si.TCPInfo.Data_segs_out != 0 {
-		fmt.Printf(" data_segs_out:%d", si.TCPInfo.Data_segs_out)
+		fmt.Fprintf(w, " data_segs_out:%d", si.TCPInfo.Data_segs_out)
 	}
 	if si.TCPInfo.Data_segs_in != 0 {
-		fmt.Printf(" data_segs_in:%d", si.TCPInfo.Data_segs_in)
+		fmt.Fprintf(w, " data_segs_in:%d", si.TCPInfo.Data_segs_in)
 	}
 
-	// DCTCP && BBRInfo
+	if si.DCTCPInfo != nil {
+		if si.DCTCPInfo.Enabled != 0 {
+			fmt.Fprintf(w, " dctcp:(ce_state:%d,alpha:%d,ab_ecn:%d,ab_tot:%d)",
+				si.DCTCPInfo.CEState, si.DCTCPInfo.Alpha, si.DCTCPInfo.ABEcn, si.DCTCPInfo.ABTot)
+		} else {
+			fmt.Fprintf(w, " dctcp:fallback_mode")
+		}
+	}
+	if si.BBRInfo != nil {
+		bw := uint64(si.BBRInfo.BwHi)<<32 | uint64(si.BBRInfo.BwLo)
+		fmt.Fprintf(w, " bbr:(bw:%sbps,mrtt:%g", BwToStr(float64(bw*8)), float64(si.BBRInfo.MinRtt)/1000)
+		if si.BBRInfo.PacingGain != 0 {
+			fmt.Fprintf(w, ",pacing_gain:%g", float64(si.BBRInfo.PacingGain)/256)
+		}
+		if si.BBRInfo.CwndGain != 0 {
+			fmt.Fprintf(w, ",cwnd_gain:%g", float64(si.BBRInfo.CwndGain)/256)
+		}
+		fmt.Fprintf(w, ")")
+	}
 
 	if si.VegasInfo != nil {
 		rtt := si.TCPInfo.Rtt
@@ -377,73 +599,357 @@ func (si *SocketInfo) TCPInfoPrint() {
 			rtt = si.VegasInfo.Rtt
 		}
 		if rtt > 0 && si.TCPInfo.Snd_mss != 0 && si.TCPInfo.Snd_cwnd != 0 {
-			fmt.Printf(" send:%sbps", BwToStr(float64(si.TCPInfo.Snd_cwnd)*float64(si.TCPInfo.Snd_mss)*8000000/float64(rtt)))
+			fmt.Fprintf(w, " send:%sbps", BwToStr(float64(si.TCPInfo.Snd_cwnd)*float64(si.TCPInfo.Snd_mss)*8000000/float64(rtt)))
 		}
 	}
 
 	if si.TCPInfo.Last_data_sent != 0 {
-		fmt.Printf(" lastsnd:%d", si.TCPInfo.Last_data_sent)
+		fmt.Fprintf(w, " lastsnd:%d", si.TCPInfo.Last_data_sent)
 	}
 	if si.TCPInfo.Last_data_recv != 0 {
-		fmt.Printf(" lastrcv:%d", si.TCPInfo.Last_data_recv)
+		fmt.Fprintf(w, " lastrcv:%d", si.TCPInfo.Last_data_recv)
 	}
 	if si.TCPInfo.Last_ack_recv != 0 {
-		fmt.Printf(" lastack:%d", si.TCPInfo.Last_ack_recv)
+		fmt.Fprintf(w, " lastack:%d", si.TCPInfo.Last_ack_recv)
 	}
 	if si.TCPInfo.Pacing_rate != 0 {
-		fmt.Printf(" pacing_rate:%sbps", BwToStr(float64(si.TCPInfo.Pacing_rate*8)))
+		fmt.Fprintf(w, " pacing_rate:%sbps", BwToStr(float64(si.TCPInfo.Pacing_rate*8)))
 		if si.TCPInfo.Max_pacing_rate != 0 {
-			fmt.Printf("/%sbps", BwToStr(float64(si.TCPInfo.Max_pacing_rate*8)))
+			fmt.Fprintf(w, "/%sbps", BwToStr(float64(si.TCPInfo.Max_pacing_rate*8)))
 		}
 	}
 	if si.TCPInfo.Delivery_rate != 0 {
-		fmt.Printf(" delivery_rate:%sbps", BwToStr(float64(si.TCPInfo.Delivery_rate*8)))
+		fmt.Fprintf(w, " delivery_rate:%sbps", BwToStr(float64(si.TCPInfo.Delivery_rate*8)))
 	}
-	if si.TCPInfo.Pad_cgo_0[1] != 0 {
-		fmt.Printf(" app_limited")
+	if si.TCPInfo.Delivered != 0 {
+		fmt.Fprintf(w, " delivered:%d", si.TCPInfo.Delivered)
 	}
-	if si.TCPInfo.Busy_time != 0 {
-		fmt.Printf(" busy:%sms", BwToStr(float64(si.TCPInfo.Busy_time/1000)))
+	if si.TCPInfo.Delivered_ce != 0 {
+		fmt.Fprintf(w, " delivered_ce:%d", si.TCPInfo.Delivered_ce)
 	}
-	if si.TCPInfo.Rwnd_limited != 0 {
-		fmt.Printf(" rwnd_limited:%sms(%.2f%%)",
-			BwToStr(float64(si.TCPInfo.Rwnd_limited/1000)),
-			100.0*float64(si.TCPInfo.Rwnd_limited)/float64(si.TCPInfo.Busy_time))
+	if si.TCPInfo.Delivery_rate_app_limited {
+		fmt.Fprintf(w, " app_limited")
 	}
-	if si.TCPInfo.Sndbuf_limited != 0 {
-		fmt.Printf(" sndbuf_limited:%sms(%.2f%%)",
-			BwToStr(float64(si.TCPInfo.Sndbuf_limited/1000)),
-			100.0*float64(si.TCPInfo.Sndbuf_limited)/float64(si.TCPInfo.Busy_time))
+	if si.TCPInfo.Busy_time != 0 {
+		fmt.Fprintf(w, " busy:%sms", BwToStr(float64(si.TCPInfo.Busy_time/1000)))
+		if si.TCPInfo.Rwnd_limited != 0 {
+			fmt.Fprintf(w, " rwnd_limited:%sms(%.2f%%)",
+				BwToStr(float64(si.TCPInfo.Rwnd_limited/1000)),
+				100.0*float64(si.TCPInfo.Rwnd_limited)/float64(si.TCPInfo.Busy_time))
+		}
+		if si.TCPInfo.Sndbuf_limited != 0 {
+			fmt.Fprintf(w, " sndbuf_limited:%sms(%.2f%%)",
+				BwToStr(float64(si.TCPInfo.Sndbuf_limited/1000)),
+				100.0*float64(si.TCPInfo.Sndbuf_limited)/float64(si.TCPInfo.Busy_time))
+		}
 	}
 	if si.TCPInfo.Unacked != 0 {
-		fmt.Printf(" unacked:%d", si.TCPInfo.Unacked)
+		fmt.Fprintf(w, " unacked:%d", si.TCPInfo.Unacked)
 	}
 	if si.TCPInfo.Retrans != 0 || si.TCPInfo.Total_retrans != 0 {
-		fmt.Printf(" retrans:%d/%d", si.TCPInfo.Retrans, si.TCPInfo.Total_retrans)
+		fmt.Fprintf(w, " retrans:%d/%d", si.TCPInfo.Retrans, si.TCPInfo.Total_retrans)
 	}
 	if si.TCPInfo.Lost != 0 {
-		fmt.Printf(" lost:%d", si.TCPInfo.Lost)
+		fmt.Fprintf(w, " lost:%d", si.TCPInfo.Lost)
 	}
 	if si.TCPInfo.Sacked != 0 && si.Status != SsLISTEN {
-		fmt.Printf(" sacked:%d", si.TCPInfo.Sacked)
+		fmt.Fprintf(w, " sacked:%d", si.TCPInfo.Sacked)
+	}
+	if si.TCPInfo.Dsack_dups != 0 {
+		fmt.Fprintf(w, " dsack_dups:%d", si.TCPInfo.Dsack_dups)
 	}
 	if si.TCPInfo.Fackets != 0 {
-		fmt.Printf(" fackets:%d", si.TCPInfo.Fackets)
+		fmt.Fprintf(w, " fackets:%d", si.TCPInfo.Fackets)
 	}
 	if si.TCPInfo.Reordering != 3 {
-		fmt.Printf(" reordering:%d", si.TCPInfo.Reordering)
+		fmt.Fprintf(w, " reordering:%d", si.TCPInfo.Reordering)
+	}
+	if si.TCPInfo.Reord_seen != 0 {
+		fmt.Fprintf(w, " reord_seen:%d", si.TCPInfo.Reord_seen)
 	}
 	if si.TCPInfo.Rcv_rtt != 0 {
-		fmt.Printf(" rcv_rtt:%.2f", float64(si.TCPInfo.Rcv_rtt)/1000)
+		fmt.Fprintf(w, " rcv_rtt:%.2f", float64(si.TCPInfo.Rcv_rtt)/1000)
 	}
 	if si.TCPInfo.Rcv_space != 0 {
-		fmt.Printf(" rcv_space:%d", si.TCPInfo.Rcv_space)
+		fmt.Fprintf(w, " rcv_space:%d", si.TCPInfo.Rcv_space)
 	}
 	if si.TCPInfo.Notsent_bytes != 0 {
-		fmt.Printf(" notsent:%d", si.TCPInfo.Notsent_bytes)
+		fmt.Fprintf(w, " notsent:%d", si.TCPInfo.Notsent_bytes)
 	}
 	if si.TCPInfo.Min_rtt != 0 && si.TCPInfo.Min_rtt != math.MaxUint32 {
-		fmt.Printf(" minrtt:%s", BwToStr(float64(si.TCPInfo.Min_rtt)/1000))
+		fmt.Fprintf(w, " minrtt:%s", BwToStr(float64(si.TCPInfo.Min_rtt)/1000))
+	}
+	if si.TCPInfo.Rcv_ooopack != 0 {
+		fmt.Fprintf(w, " rcv_ooopack:%d", si.TCPInfo.Rcv_ooopack)
+	}
+	if si.TCPInfo.Snd_wnd != 0 {
+		fmt.Fprintf(w, " snd_wnd:%d", si.TCPInfo.Snd_wnd)
+	}
+	if si.TCPInfo.Rcv_wnd != 0 {
+		fmt.Fprintf(w, " rcv_wnd:%d", si.TCPInfo.Rcv_wnd)
+	}
+	if si.TCPInfo.Rehash != 0 {
+		fmt.Fprintf(w, " rehash:%d", si.TCPInfo.Rehash)
+	}
+	if len(si.MD5Sig) > 0 {
+		fmt.Fprintf(w, " md5keys:")
+		for i := range si.MD5Sig {
+			if i > 0 {
+				fmt.Fprintf(w, ",")
+			}
+			fmt.Fprintf(w, "%s", si.MD5Sig[i].String())
+		}
+	}
+	if len(si.ULPName) > 0 {
+		fmt.Fprintf(w, " tcp-ulp-%s", si.ULPName)
+	}
+	if si.TLSInfo != nil {
+		si.TLSInfo.Print(w)
+	}
+	fmt.Fprintf(w, " )]    ")
+}
+
+func (si *SocketInfo) SCTPAddrsPrint(w io.Writer) {
+	if len(si.LocalAddrs) > 1 {
+		fmt.Fprintf(w, "[laddrs:(")
+		for i := range si.LocalAddrs {
+			if i > 0 {
+				fmt.Fprintf(w, ",")
+			}
+			fmt.Fprintf(w, "%s", si.LocalAddrs[i].Addr())
+		}
+		fmt.Fprintf(w, ")]    ")
+	}
+	if len(si.RemoteAddrs) > 1 {
+		fmt.Fprintf(w, "[raddrs:(")
+		for i := range si.RemoteAddrs {
+			if i > 0 {
+				fmt.Fprintf(w, ",")
+			}
+			fmt.Fprintf(w, "%s", si.RemoteAddrs[i].Addr())
+		}
+		fmt.Fprintf(w, ")]    ")
+	}
+}
+
+func (si *SocketInfo) SCTPInfoPrint(w io.Writer) {
+	fmt.Fprintf(w, "[sctp:(")
+	if si.SCTPInfo.Tag != 0 {
+		fmt.Fprintf(w, " tag:%x", si.SCTPInfo.Tag)
+	}
+	if si.SCTPInfo.Rwnd != 0 {
+		fmt.Fprintf(w, " rwnd:%d", si.SCTPInfo.Rwnd)
+	}
+	if si.SCTPInfo.Unackdata != 0 {
+		fmt.Fprintf(w, " unackdata:%d", si.SCTPInfo.Unackdata)
+	}
+	if si.SCTPInfo.Penddata != 0 {
+		fmt.Fprintf(w, " penddata:%d", si.SCTPInfo.Penddata)
+	}
+	if si.SCTPInfo.Instrms != 0 || si.SCTPInfo.Outstrms != 0 {
+		fmt.Fprintf(w, " streams:%d/%d", si.SCTPInfo.Instrms, si.SCTPInfo.Outstrms)
+	}
+	if si.SCTPInfo.FragmentationPoint != 0 {
+		fmt.Fprintf(w, " fragpoint:%d", si.SCTPInfo.FragmentationPoint)
+	}
+	if si.SCTPInfo.Inqueue != 0 || si.SCTPInfo.Outqueue != 0 {
+		fmt.Fprintf(w, " queue:%d/%d", si.SCTPInfo.Inqueue, si.SCTPInfo.Outqueue)
+	}
+	if si.SCTPInfo.Maxseg != 0 {
+		fmt.Fprintf(w, " maxseg:%d", si.SCTPInfo.Maxseg)
+	}
+	if si.SCTPInfo.PeerRwnd != 0 {
+		fmt.Fprintf(w, " peer_rwnd:%d", si.SCTPInfo.PeerRwnd)
+	}
+	if si.SCTPInfo.PeerTag != 0 {
+		fmt.Fprintf(w, " peer_tag:%x", si.SCTPInfo.PeerTag)
+	}
+	if si.SCTPInfo.Rtxchunks != 0 {
+		fmt.Fprintf(w, " rtxchunks:%d", si.SCTPInfo.Rtxchunks)
+	}
+	if si.SCTPInfo.Opackets != 0 || si.SCTPInfo.Ipackets != 0 {
+		fmt.Fprintf(w, " packets:%d/%d", si.SCTPInfo.Opackets, si.SCTPInfo.Ipackets)
+	}
+	if si.SCTPInfo.PCwnd != 0 {
+		fmt.Fprintf(w, " cwnd:%d", si.SCTPInfo.PCwnd)
+	}
+	if si.SCTPInfo.PSrtt != 0 {
+		fmt.Fprintf(w, " srtt:%d", si.SCTPInfo.PSrtt)
+	}
+	if si.SCTPInfo.PRto != 0 {
+		fmt.Fprintf(w, " rto:%d", si.SCTPInfo.PRto)
+	}
+	if si.SCTPInfo.PSsthresh != 0 {
+		fmt.Fprintf(w, " ssthresh:%d", si.SCTPInfo.PSsthresh)
+	}
+	if si.SCTPInfo.PFlightSize != 0 {
+		fmt.Fprintf(w, " flight_size:%d", si.SCTPInfo.PFlightSize)
+	}
+	fmt.Fprintf(w, " )]    ")
+}
+
+func (si *SocketInfo) MPTCPInfoPrint(w io.Writer) {
+	fmt.Fprintf(w, "[mptcp:(")
+	fmt.Fprintf(w, " subflows:%d", si.MPTCPInfo.Subflows)
+	if si.MPTCPInfo.SubflowsMax != 0 {
+		fmt.Fprintf(w, " subflows_max:%d", si.MPTCPInfo.SubflowsMax)
+	}
+	if si.MPTCPInfo.AddAddrSignal != 0 {
+		fmt.Fprintf(w, " add_addr_signal:%d", si.MPTCPInfo.AddAddrSignal)
+	}
+	if si.MPTCPInfo.AddAddrAccepted != 0 {
+		fmt.Fprintf(w, " add_addr_accepted:%d", si.MPTCPInfo.AddAddrAccepted)
+	}
+	if si.MPTCPInfo.AddAddrSignalMax != 0 {
+		fmt.Fprintf(w, " add_addr_signal_max:%d", si.MPTCPInfo.AddAddrSignalMax)
+	}
+	if si.MPTCPInfo.AddAddrAcceptedMax != 0 {
+		fmt.Fprintf(w, " add_addr_accepted_max:%d", si.MPTCPInfo.AddAddrAcceptedMax)
+	}
+	if si.MPTCPInfo.Flags&MPTCP_INFO_FLAG_FALLBACK != 0 {
+		fmt.Fprintf(w, " fallback")
+	}
+	if si.MPTCPInfo.Flags&MPTCP_INFO_FLAG_REMOTE_KEY_RECEIVED != 0 {
+		fmt.Fprintf(w, " remote_key")
+	}
+	if si.MPTCPInfo.Token != 0 {
+		fmt.Fprintf(w, " token:%x", si.MPTCPInfo.Token)
+	}
+	if si.MPTCPInfo.WriteSeq != 0 {
+		fmt.Fprintf(w, " write_seq:%x", si.MPTCPInfo.WriteSeq)
+	}
+	if si.MPTCPInfo.SndUna != 0 {
+		fmt.Fprintf(w, " snd_una:%x", si.MPTCPInfo.SndUna)
+	}
+	if si.MPTCPInfo.RcvNxt != 0 {
+		fmt.Fprintf(w, " rcv_nxt:%x", si.MPTCPInfo.RcvNxt)
+	}
+	if si.MPTCPInfo.LocalAddrUsed != 0 || si.MPTCPInfo.LocalAddrMax != 0 {
+		fmt.Fprintf(w, " local_addr_used:%d local_addr_max:%d", si.MPTCPInfo.LocalAddrUsed, si.MPTCPInfo.LocalAddrMax)
+	}
+	if si.MPTCPInfo.CsumEnabled != 0 {
+		fmt.Fprintf(w, " csum_enabled")
+	}
+	if si.MPTCPInfo.Retransmits != 0 {
+		fmt.Fprintf(w, " retransmits:%d", si.MPTCPInfo.Retransmits)
+	}
+	if si.MPTCPInfo.BytesRetrans != 0 {
+		fmt.Fprintf(w, " bytes_retrans:%d", si.MPTCPInfo.BytesRetrans)
+	}
+	if si.MPTCPInfo.BytesSent != 0 {
+		fmt.Fprintf(w, " bytes_sent:%d", si.MPTCPInfo.BytesSent)
+	}
+	if si.MPTCPInfo.BytesReceived != 0 {
+		fmt.Fprintf(w, " bytes_received:%d", si.MPTCPInfo.BytesReceived)
+	}
+	if si.MPTCPInfo.BytesAcked != 0 {
+		fmt.Fprintf(w, " bytes_acked:%d", si.MPTCPInfo.BytesAcked)
+	}
+	if si.MPTCPInfo.SubflowsTotal != 0 {
+		fmt.Fprintf(w, " subflows_total:%d", si.MPTCPInfo.SubflowsTotal)
+	}
+	fmt.Fprintf(w, " )]    ")
+}
+
+func (si *SocketInfo) PacketInfoPrint(w io.Writer) {
+	fmt.Fprintf(w, "[packet:(")
+	if si.PacketInfo != nil {
+		fmt.Fprintf(w, " ver:%d", si.PacketInfo.Version+1)
+		if si.PacketInfo.Flags&PDI_RUNNING != 0 {
+			fmt.Fprintf(w, " running")
+		}
+		if si.PacketInfo.Flags&PDI_AUXDATA != 0 {
+			fmt.Fprintf(w, " auxdata")
+		}
+		if si.PacketInfo.Flags&PDI_ORIGDEV != 0 {
+			fmt.Fprintf(w, " origdev")
+		}
+		if si.PacketInfo.Flags&PDI_VNETHDR != 0 {
+			fmt.Fprintf(w, " vnethdr")
+		}
+		if si.PacketInfo.Flags&PDI_LOSS != 0 {
+			fmt.Fprintf(w, " loss")
+		}
+		if si.PacketInfo.CopyThresh != 0 {
+			fmt.Fprintf(w, " copy_thresh:%d", si.PacketInfo.CopyThresh)
+		}
+	}
+	if si.PacketRxRing != nil {
+		fmt.Fprintf(w, " rx_ring:%d*%d/%d*%d", si.PacketRxRing.BlockNr, si.PacketRxRing.BlockSize, si.PacketRxRing.FrameNr, si.PacketRxRing.FrameSize)
+	}
+	if si.PacketTxRing != nil {
+		fmt.Fprintf(w, " tx_ring:%d*%d/%d*%d", si.PacketTxRing.BlockNr, si.PacketTxRing.BlockSize, si.PacketTxRing.FrameNr, si.PacketTxRing.FrameSize)
+	}
+	if si.PacketFanout != nil {
+		fanoutType := int(*si.PacketFanout >> 16 & 0xff)
+		if fanoutType < len(PacketFanoutType) {
+			fmt.Fprintf(w, " fanout:%d/%s", *si.PacketFanout&0xffff, PacketFanoutType[fanoutType])
+		} else {
+			fmt.Fprintf(w, " fanout:%d/%d", *si.PacketFanout&0xffff, fanoutType)
+		}
+	}
+	if len(si.PacketMclist) > 0 {
+		fmt.Fprintf(w, " mclist:%d", len(si.PacketMclist))
+	}
+	if len(si.PacketFilter) > 0 {
+		fmt.Fprintf(w, " bpf:%d", len(si.PacketFilter))
+	}
+	fmt.Fprintf(w, " )]    ")
+}
+
+func (si *SocketInfo) NetlinkInfoPrint(w io.Writer) {
+	fmt.Fprintf(w, "[netlink:(")
+	if len(si.NetlinkGroups) > 0 {
+		fmt.Fprintf(w, " groups:")
+		for i, group := range si.NetlinkGroups {
+			if i > 0 {
+				fmt.Fprintf(w, ",")
+			}
+			fmt.Fprintf(w, "%d", group)
+		}
+	}
+	if si.NetlinkFlags&NDIAG_FLAG_CB_RUNNING != 0 {
+		fmt.Fprintf(w, " cb_running")
+	}
+	if si.NetlinkFlags&NDIAG_FLAG_PKTINFO != 0 {
+		fmt.Fprintf(w, " pktinfo")
+	}
+	if si.NetlinkFlags&NDIAG_FLAG_BROADCAST_ERROR != 0 {
+		fmt.Fprintf(w, " broadcast_error")
+	}
+	if si.NetlinkFlags&NDIAG_FLAG_NO_ENOBUFS != 0 {
+		fmt.Fprintf(w, " no_enobufs")
+	}
+	if si.NetlinkFlags&NDIAG_FLAG_LISTEN_ALL_NSID != 0 {
+		fmt.Fprintf(w, " listen_all_nsid")
+	}
+	if si.NetlinkFlags&NDIAG_FLAG_CAP_ACK != 0 {
+		fmt.Fprintf(w, " cap_ack")
+	}
+	fmt.Fprintf(w, " )]    ")
+}
+
+func (si *SocketInfo) UnixInfoPrint(w io.Writer) {
+	fmt.Fprintf(w, "[detail:(ino:%d,sk:%x", si.Inode, si.SK)
+	if si.UnixVFS != nil {
+		fmt.Fprintf(w, ",vfs:%d:%d/%d", si.UnixVFS.Dev>>20, si.UnixVFS.Dev&0xfffff, si.UnixVFS.Ino)
+	}
+	if len(si.UnixIcons) > 0 {
+		fmt.Fprintf(w, ",pending:%v", si.UnixIcons)
+	}
+	if si.Shutdown != 0 {
+		fmt.Fprintf(w, ",shutdown:")
+		if si.Shutdown&1 != 0 {
+			fmt.Fprintf(w, "-")
+		} else {
+			fmt.Fprintf(w, "<")
+		}
+		fmt.Fprintf(w, "-")
+		if si.Shutdown&2 != 0 {
+			fmt.Fprintf(w, "-")
+		} else {
+			fmt.Fprintf(w, ">")
+		}
 	}
-	fmt.Printf(" )]\n")
+	fmt.Fprintf(w, ")]    ")
 }
@@ -4,7 +4,11 @@ package psss
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"net/netip"
 	"os"
 	"strconv"
 	"strings"
@@ -24,14 +28,19 @@ const (
 	ProtocalTCP
 	ProtocalUDP
 	ProtocalUnix
+	ProtocalUDPLite
+	ProtocalMPTCP
 	ProtocalMax
 )
 
 const (
 	SOCK_DIAG_BY_FAMILY = 20
+	SOCK_DESTROY        = 21
 
 	SizeOfUnixDiagRequest = 40
 	SizeOfUnixDiagMsg     = 16
+	SizeOfUnixDiagVFS     = 8
+	SizeOfUnixDiagRQlen   = 8
 	SizeOfInetDiagRequest = 72
 	SizeOfInetDiagMsg     = 72
 )
@@ -54,9 +63,99 @@ const (
 	INET_DIAG_PAD
 	INET_DIAG_MARK
 	INET_DIAG_BBRINFO
+	INET_DIAG_CLASS_ID
+	INET_DIAG_MD5SIG
+	INET_DIAG_ULP_INFO
+	INET_DIAG_SK_BPF_STORAGES
+	INET_DIAG_CGROUP_ID
+	INET_DIAG_SOCKOPT
 	INET_DIAG_MAX
 )
 
+const (
+	INET_ULP_INFO_UNSPEC = iota
+	INET_ULP_INFO_NAME
+	INET_ULP_INFO_TLS
+	INET_ULP_INFO_MPTCP
+)
+
+const (
+	TLS_INFO_UNSPEC = iota
+	TLS_INFO_VERSION
+	TLS_INFO_CIPHER
+	TLS_INFO_TXCONF
+	TLS_INFO_RXCONF
+	TLS_INFO_ZC_RO_TX
+	TLS_INFO_RX_NO_PAD
+)
+
+const (
+	TLS_CONF_BASE = iota + 1
+	TLS_CONF_SW
+	TLS_CONF_HW
+	TLS_CONF_HW_RECORD
+)
+
+// bits of struct inet_diag_sockopt
+const (
+	INET_DIAG_SOCKOPT_RECVERR = 1 << iota
+	INET_DIAG_SOCKOPT_IS_ICSK
+	INET_DIAG_SOCKOPT_FREEBIND
+	INET_DIAG_SOCKOPT_HDRINCL
+	INET_DIAG_SOCKOPT_MC_LOOP
+	INET_DIAG_SOCKOPT_TRANSPARENT
+	INET_DIAG_SOCKOPT_MC_ALL
+	INET_DIAG_SOCKOPT_NODEFRAG
+	INET_DIAG_SOCKOPT_BIND_ADDRESS_NO_PORT
+	INET_DIAG_SOCKOPT_RECVERR_RFC4884
+	INET_DIAG_SOCKOPT_DEFER_CONNECT
+)
+
+var (
+	TLSVersionName = map[uint16]string{
+		0x0303: "1.2",
+		0x0304: "1.3",
+	}
+
+	TLSCipherName = map[uint16]string{
+		51: "aes-gcm-128",
+		52: "aes-gcm-256",
+		53: "aes-ccm-128",
+		54: "chacha20-poly1305",
+		55: "sm4-gcm",
+		56: "sm4-ccm",
+		57: "aria-gcm-128",
+		58: "aria-gcm-256",
+	}
+
+	TLSConfName = []string{
+		"none",
+		"base",
+		"sw",
+		"hw",
+		"hw-record",
+	}
+
+	SockOptName = []string{
+		"recverr",
+		"is_icsk",
+		"freebind",
+		"hdrincl",
+		"mc_loop",
+		"transparent",
+		"mc_all",
+		"nodefrag",
+		"bind_address_no_port",
+		"recverr_rfc4884",
+		"defer_connect",
+	}
+)
+
+const (
+	TCP_MD5SIG_MAXKEYLEN = 80
+	SizeOfTCPDiagMD5Sig  = 100
+)
+
 const (
 	TCPI_OPT_TIMESTAMPS = 1
 	TCPI_OPT_SACK       = 2
@@ -88,15 +187,21 @@ const (
 
 var (
 	procFilePath = map[string]string{
-		"sockstat4": "/proc/net/sockstat",
-		"sockstat6": "/proc/net/sockstat6",
-		"TCP4":      "/proc/net/tcp",
-		"TCP6":      "/proc/net/tcp6",
-		"UDP4":      "/proc/net/udp",
-		"UDP6":      "/proc/net/udp6",
-		"RAW4":      "/proc/net/raw",
-		"RAW6":      "/proc/net/raw6",
-		"Unix":      "/proc/net/unix",
+		"sockstat4":  "net/sockstat",
+		"sockstat6":  "net/sockstat6",
+		"TCP4":       "net/tcp",
+		"TCP6":       "net/tcp6",
+		"UDP4":       "net/udp",
+		"UDP6":       "net/udp6",
+		"UDPLITE4":   "net/udplite",
+		"UDPLITE6":   "net/udplite6",
+		"RAW4":       "net/raw",
+		"RAW6":       "net/raw6",
+		"Unix":       "net/unix",
+		"Packet":     "net/packet",
+		"Netlink":    "net/netlink",
+		"SCTPEps":    "net/sctp/eps",
+		"SCTPAssocs": "net/sctp/assocs",
 	}
 
 	UnixSstate = []uint8{
@@ -116,6 +221,29 @@ type InetDiagSockID struct {
 	IdiagCookie [2]uint32
 }
 
+func ntohs(v uint16) uint16 {
+	return v>>8 | v<<8
+}
+
+func diagAddr(raw *[4]uint32, family uint8) netip.Addr {
+	b := *(*[16]byte)(unsafe.Pointer(raw))
+	if family == unix.AF_INET {
+		return netip.AddrFrom4([4]byte{b[0], b[1], b[2], b[3]})
+	}
+	return netip.AddrFrom16(b)
+}
+
+func (id *InetDiagSockID) Local(family uint8) SockAddr {
+	return SockAddr{
+		AddrPort: netip.AddrPortFrom(diagAddr(&id.IdiagSrc, family), ntohs(id.IdiagSport)),
+		IfIndex:  id.IdiagIF,
+	}
+}
+
+func (id *InetDiagSockID) Remote(family uint8) SockAddr {
+	return SockAddr{AddrPort: netip.AddrPortFrom(diagAddr(&id.IdiagDst, family), ntohs(id.IdiagDport))}
+}
+
 type InetDiagReq struct {
 	SdiagFamily   uint8
 	SdiagProtocol uint8
@@ -143,59 +271,80 @@ type InetDiagMessage struct {
 	IdiagInode   uint32
 }
 
-type TCPInfo struct {
-	State           uint8
-	Ca_state        uint8
-	Retransmits     uint8
-	Probes          uint8
-	Backoff         uint8
-	Options         uint8
-	Pad_cgo_0       [2]byte
-	Rto             uint32
-	Ato             uint32
-	Snd_mss         uint32
-	Rcv_mss         uint32
-	Unacked         uint32
-	Sacked          uint32
-	Lost            uint32
-	Retrans         uint32
-	Fackets         uint32
-	Last_data_sent  uint32
-	Last_ack_sent   uint32
-	Last_data_recv  uint32
-	Last_ack_recv   uint32
-	Pmtu            uint32
-	Rcv_ssthresh    uint32
-	Rtt             uint32
-	Rttvar          uint32
-	Snd_ssthresh    uint32
-	Snd_cwnd        uint32
-	Advmss          uint32
-	Reordering      uint32
-	Rcv_rtt         uint32
-	Rcv_space       uint32
-	Total_retrans   uint32
-	Pacing_rate     uint64
-	Max_pacing_rate uint64
-	Bytes_acked     uint64 /* RFC4898 tcpEStatsAppHCThruOctetsAcked */
-	Bytes_received  uint64 /* RFC4898 tcpEStatsAppHCThruOctetsReceived */
-	Segs_out        uint32 /* RFC4898 tcpEStatsPerfSegsOut */
-	Segs_in         uint32 /* RFC4898 tcpEStatsPerfSegsIn */
-	Notsent_bytes   uint32
-	Min_rtt         uint32
-	Data_segs_in    uint32 /* RFC4898 tcpEStatsDataSegsIn */
-	Data_segs_out   uint32 /* RFC4898 tcpEStatsDataSegsOut */
-	Delivery_rate   uint64
-	Busy_time       uint64 /* Time (usec) busy sending data */
-	Rwnd_limited    uint64 /* Time (usec) limited by receive window */
-	Sndbuf_limited  uint64 /* Time (usec) limited by send buffer */
+type TCPVegasInfo struct {
+	Enabled uint32 `json:"enabled"`
+	Rttcnt  uint32 `json:"rttcnt"`
+	Rtt     uint32 `json:"rtt"`
+	Minrtt  uint32 `json:"minrtt"`
 }
 
-type TCPVegasInfo struct {
-	Enabled uint32
-	Rttcnt  uint32
-	Rtt     uint32
-	Minrtt  uint32
+type TCPDCTCPInfo struct {
+	Enabled uint16 `json:"enabled"`
+	CEState uint16 `json:"ce_state"`
+	Alpha   uint32 `json:"alpha"`
+	ABEcn   uint32 `json:"ab_ecn"`
+	ABTot   uint32 `json:"ab_tot"`
+}
+
+type TCPBBRInfo struct {
+	BwLo       uint32 `json:"bw_lo"`
+	BwHi       uint32 `json:"bw_hi"`
+	MinRtt     uint32 `json:"min_rtt"`
+	PacingGain uint32 `json:"pacing_gain"`
+	CwndGain   uint32 `json:"cwnd_gain"`
+}
+
+type TCPDiagMD5Sig struct {
+	Family    uint8
+	PrefixLen uint8
+	KeyLen    uint16
+	Addr      [16]byte
+	Key       [TCP_MD5SIG_MAXKEYLEN]byte
+}
+
+type TLSInfo struct {
+	Version uint16
+	Cipher  uint16
+	TxConf  uint16
+	RxConf  uint16
+	ZcRoTx  bool
+	RxNoPad bool
+}
+
+// String shows the address and prefix a key is used for, never the key.
+func (m *TCPDiagMD5Sig) String() string {
+	var addr netip.Addr
+	if m.Family == unix.AF_INET {
+		addr = netip.AddrFrom4([4]byte{m.Addr[0], m.Addr[1], m.Addr[2], m.Addr[3]})
+	} else {
+		addr = netip.AddrFrom16(m.Addr)
+	}
+	return fmt.Sprintf("%s/%d", addr, m.PrefixLen)
+}
+
+func (t *TLSInfo) Print(w io.Writer) {
+	if name, ok := TLSVersionName[t.Version]; ok {
+		fmt.Fprintf(w, " version:%s", name)
+	} else {
+		fmt.Fprintf(w, " version:%d.%d", t.Version>>8, t.Version&0xff)
+	}
+	if name, ok := TLSCipherName[t.Cipher]; ok {
+		fmt.Fprintf(w, " cipher:%s", name)
+	} else {
+		fmt.Fprintf(w, " cipher:%d", t.Cipher)
+	}
+	if int(t.RxConf) < len(TLSConfName) {
+		fmt.Fprintf(w, " rxconf:%s", TLSConfName[t.RxConf])
+	}
+	if int(t.TxConf) < len(TLSConfName) {
+		fmt.Fprintf(w, " txconf:%s", TLSConfName[t.TxConf])
+	}
+	if t.ZcRoTx {
+		fmt.Fprintf(w, " zc_ro_tx")
+	}
+	if t.RxNoPad {
+		fmt.Fprintf(w, " rx_no_pad")
+	}
 }
 
 type InetDiagMeminfo struct {
@@ -205,175 +354,307 @@ type InetDiagMeminfo struct {
 	IdiagTmem uint32
 }
 
-func SendInetDiagMsg(af uint8, protocal uint8, exts uint8, states uint32) (skfd int, err error) {
-	if skfd, err = unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW, unix.NETLINK_SOCK_DIAG); err != nil {
-		return -1, err
+func newInetDiagRequest(af uint8, protocal int, exts uint8, states uint32, bytecode []byte) []byte {
+	var req InetDiagRequest
+	req.Header.Type = SOCK_DIAG_BY_FAMILY
+	req.Header.Flags = unix.NLM_F_DUMP | unix.NLM_F_REQUEST
+	req.Request.SdiagFamily = af
+	if protocal <= 0xff {
+		req.Request.SdiagProtocol = uint8(protocal)
+	}
+	req.Request.IdiagExt = exts
+	req.Request.IdiagStates = states
+	length := SizeOfInetDiagRequest
+	if len(bytecode) > 0 {
+		length += unix.SizeofRtAttr + rtaAlign(len(bytecode))
+	}
+	if protocal > 0xff {
+		// protocols that do not fit in sdiag_protocol, e.g. IPPROTO_MPTCP. It
+		// is left 0, which no handler takes, so a kernel that ignores the
+		// attribute answers ENOENT instead of the sockets of the low byte
+		length += unix.SizeofRtAttr + 4
 	}
-	sockAddrNl.Family = unix.AF_NETLINK
-	inDiagReq.Header.Type = SOCK_DIAG_BY_FAMILY
-	inDiagReq.Header.Flags = unix.NLM_F_DUMP | unix.NLM_F_REQUEST
-	inDiagReq.Request.SdiagFamily = af
-	inDiagReq.Request.SdiagProtocol = protocal
-	inDiagReq.Request.IdiagExt = exts
-	inDiagReq.Request.IdiagStates = states
-	inDiagReq.Header.Len = uint32(unsafe.Sizeof(inDiagReq))
-	*(*InetDiagRequest)(unsafe.Pointer(&inDiagRequestBuffer[0])) = inDiagReq
-	if err = unix.Sendmsg(skfd, inDiagRequestBuffer, nil, &sockAddrNl, 0); err != nil {
-		return -1, err
+	request := make([]byte, length)
+	cursor := SizeOfInetDiagRequest
+	if len(bytecode) > 0 {
+		*(*unix.RtAttr)(unsafe.Pointer(&request[cursor])) = unix.RtAttr{
+			Len:  uint16(unix.SizeofRtAttr + len(bytecode)),
+			Type: INET_DIAG_REQ_BYTECODE,
+		}
+		copy(request[cursor+unix.SizeofRtAttr:], bytecode)
+		cursor += unix.SizeofRtAttr + rtaAlign(len(bytecode))
+	}
+	if protocal > 0xff {
+		*(*unix.RtAttr)(unsafe.Pointer(&request[cursor])) = unix.RtAttr{
+			Len:  unix.SizeofRtAttr + 4,
+			Type: INET_DIAG_REQ_PROTOCOL,
+		}
+		*(*uint32)(unsafe.Pointer(&request[cursor+unix.SizeofRtAttr])) = uint32(protocal)
 	}
-	return skfd, nil
+	req.Header.Len = uint32(len(request))
+	*(*InetDiagRequest)(unsafe.Pointer(&request[0])) = req
+	return request
 }
 
-func RecvInetDiagMsgMulti(skfd int) (err error) {
-	var cursor int
+func rtaAlign(length int) int {
+	return (length + unix.RTA_ALIGNTO - 1) &^ (unix.RTA_ALIGNTO - 1)
+}
+
+// recvDiagMsgMulti reads and parses one datagram of a dump, see recvDatagram.
+func recvDiagMsgMulti(skfd int, buffer *[]byte) ([]syscall.NetlinkMessage, error) {
+	data, err := recvDatagram(skfd, buffer)
+	if err != nil {
+		return nil, err
+	}
+	return syscall.ParseNetlinkMessage(data)
+}
 
+// recvDatagram reads one datagram, growing buffer when the datagram does not
+// fit in it.
+func recvDatagram(skfd int, buffer *[]byte) ([]byte, error) {
+	var (
+		n   int
+		err error
+	)
 	for {
-		if bytesCounter, _, _, _, err = unix.Recvmsg(skfd, sockDiagMsgBuffer, nil, unix.MSG_PEEK); err != nil {
-			return err
+		if n, _, _, _, err = unix.Recvmsg(skfd, *buffer, nil, unix.MSG_PEEK); err != nil {
+			return nil, err
 		}
-		if bytesCounter < len(sockDiagMsgBuffer) {
+		if n < len(*buffer) {
 			break
 		}
-		sockDiagMsgBuffer = make([]byte, 2*len(sockDiagMsgBuffer))
+		*buffer = make([]byte, 2*len(*buffer))
 	}
-	if bytesCounter, _, _, _, err = unix.Recvmsg(skfd, sockDiagMsgBuffer, nil, 0); err != nil {
-		return err
+	if n, _, _, _, err = unix.Recvmsg(skfd, *buffer, nil, 0); err != nil {
+		return nil, err
 	}
-	raw, err := syscall.ParseNetlinkMessage(sockDiagMsgBuffer[:bytesCounter])
-	if err != nil {
-		return err
+	return (*buffer)[:n], nil
+}
+
+func parseInetDiagMsg(data []byte, si *SocketInfo) error {
+	var (
+		cursor int
+		nlAttr unix.NlAttr
+	)
+	if len(data) < SizeOfInetDiagMsg {
+		return fmt.Errorf("%w:[inet_diag, %d bytes]", ErrTruncatedMessage, len(data))
 	}
-	var si = NewSocketInfo()
-	for i := range raw {
-		si.Reset()
-		if raw[i].Header.Type == unix.NLMSG_DONE {
-			return ErrorDone
-		}
-		inDiagMsg = *(*InetDiagMessage)(unsafe.Pointer(&raw[i].Data[:SizeOfInetDiagMsg][0]))
-		switch inDiagMsg.IdiagFamily {
-		case unix.AF_INET:
-			si.LocalAddr.Host, _ = IPv4HexToString(strings.TrimPrefix(fmt.Sprintf("%08x", inDiagMsg.ID.IdiagSrc[0]), "0x"))
-			si.RemoteAddr.Host, _ = IPv4HexToString(strings.TrimPrefix(fmt.Sprintf("%08x", inDiagMsg.ID.IdiagDst[0]), "0x"))
-		case unix.AF_INET6:
-			si.LocalAddr.Host, _ = IPv6HexToString(
-				strings.TrimPrefix(fmt.Sprintf("%08x", inDiagMsg.ID.IdiagSrc[0]), "0x") +
-					strings.TrimPrefix(fmt.Sprintf("%08x", inDiagMsg.ID.IdiagSrc[1]), "0x") +
-					strings.TrimPrefix(fmt.Sprintf("%08x", inDiagMsg.ID.IdiagSrc[2]), "0x") +
-					strings.TrimPrefix(fmt.Sprintf("%08x", inDiagMsg.ID.IdiagSrc[3]), "0x"),
-			)
-			si.RemoteAddr.Host, _ = IPv6HexToString(
-				strings.TrimPrefix(fmt.Sprintf("%08x", inDiagMsg.ID.IdiagDst[0]), "0x") +
-					strings.TrimPrefix(fmt.Sprintf("%08x", inDiagMsg.ID.IdiagDst[1]), "0x") +
-					strings.TrimPrefix(fmt.Sprintf("%08x", inDiagMsg.ID.IdiagDst[2]), "0x") +
-					strings.TrimPrefix(fmt.Sprintf("%08x", inDiagMsg.ID.IdiagDst[3]), "0x"),
-			)
-		}
-		si.LocalAddr.Port = fmt.Sprintf("%d", (inDiagMsg.ID.IdiagSport&0xff00)>>8+(inDiagMsg.ID.IdiagSport&0xff)<<8)
-		si.RemoteAddr.Port = fmt.Sprintf("%d", (inDiagMsg.ID.IdiagDport&0xff00)>>8+(inDiagMsg.ID.IdiagDport&0xff)<<8)
-		si.Status = inDiagMsg.IdiagState
-		si.RxQueue = inDiagMsg.IdiagRqueue
-		si.TxQueue = inDiagMsg.IdiagWqueue
-		si.Timer = int(inDiagMsg.IdiagTimer)
-		si.Timeout = int(inDiagMsg.IdiagExpires)
-		si.Retransmit = int(inDiagMsg.IdiagRetrans)
-		si.UID = uint64(inDiagMsg.IdiagUid)
-		si.Inode = inDiagMsg.IdiagInode
-		si.RefCount = int(inDiagMsg.ID.IdiagIF)
-		si.SK = uint64(inDiagMsg.ID.IdiagCookie[1])<<32 | uint64(inDiagMsg.ID.IdiagCookie[0])
-		cursor = SizeOfInetDiagMsg
-		for cursor+4 < len(raw[i].Data) {
-			for raw[i].Data[cursor] == byte(0) {
-				cursor++
+	inDiagMsg := *(*InetDiagMessage)(unsafe.Pointer(&data[0]))
+	si.LocalAddr = inDiagMsg.ID.Local(inDiagMsg.IdiagFamily)
+	si.RemoteAddr = inDiagMsg.ID.Remote(inDiagMsg.IdiagFamily)
+	si.Status = inDiagMsg.IdiagState
+	si.RxQueue = inDiagMsg.IdiagRqueue
+	si.TxQueue = inDiagMsg.IdiagWqueue
+	si.Timer = int(inDiagMsg.IdiagTimer)
+	si.Timeout = int(inDiagMsg.IdiagExpires)
+	si.Retransmit = int(inDiagMsg.IdiagRetrans)
+	si.UID = uint64(inDiagMsg.IdiagUid)
+	si.Inode = inDiagMsg.IdiagInode
+	si.SK = uint64(inDiagMsg.ID.IdiagCookie[1])<<32 | uint64(inDiagMsg.ID.IdiagCookie[0])
+	cursor = SizeOfInetDiagMsg
+	for cursor+unix.SizeofNlAttr <= len(data) {
+		nlAttr = *(*unix.NlAttr)(unsafe.Pointer(&data[cursor]))
+		if nlAttr.Len < unix.SizeofNlAttr || cursor+int(nlAttr.Len) > len(data) {
+			break
+		}
+		payload := data[cursor+unix.SizeofNlAttr : cursor+int(nlAttr.Len)]
+		switch nlAttr.Type {
+		case INET_DIAG_MEMINFO:
+			si.InetMeminfo = new(InetDiagMeminfo)
+			copy((*[unsafe.Sizeof(InetDiagMeminfo{})]byte)(unsafe.Pointer(si.InetMeminfo))[:], payload)
+		case INET_DIAG_INFO:
+			switch si.Protocal {
+			case ProtocalSCTP:
+				si.SCTPInfo = new(SCTPInfo)
+				copy((*[SizeOfSCTPInfo]byte)(unsafe.Pointer(si.SCTPInfo))[:], payload)
+			case ProtocalMPTCP:
+				si.MPTCPInfo = new(MPTCPInfo)
+				copy((*[SizeOfMPTCPInfo]byte)(unsafe.Pointer(si.MPTCPInfo))[:], payload)
+			default:
+				si.TCPInfo = ParseTCPInfo(payload)
+			}
+		case INET_DIAG_VEGASINFO:
+			si.VegasInfo = new(TCPVegasInfo)
+			copy((*[unsafe.Sizeof(TCPVegasInfo{})]byte)(unsafe.Pointer(si.VegasInfo))[:], payload)
+		case INET_DIAG_DCTCPINFO:
+			si.DCTCPInfo = new(TCPDCTCPInfo)
+			copy((*[unsafe.Sizeof(TCPDCTCPInfo{})]byte)(unsafe.Pointer(si.DCTCPInfo))[:], payload)
+		case INET_DIAG_BBRINFO:
+			si.BBRInfo = new(TCPBBRInfo)
+			copy((*[unsafe.Sizeof(TCPBBRInfo{})]byte)(unsafe.Pointer(si.BBRInfo))[:], payload)
+		case INET_DIAG_CONG:
+			si.CONG = make([]byte, 0)
+			si.CONG = append(si.CONG, bytes.TrimRight(payload, "\x00")...)
+		case INET_DIAG_TOS:
+			if len(payload) >= 1 {
+				si.TOS = payload[0]
+			}
+		case INET_DIAG_TCLASS:
+			if len(payload) >= 1 {
+				si.TClass = payload[0]
 			}
-			nlAttr = *(*unix.NlAttr)(unsafe.Pointer(&raw[i].Data[cursor : cursor+unix.SizeofNlAttr][0]))
-			switch nlAttr.Type {
-			case INET_DIAG_MEMINFO:
-				// meminfo := *(*InetDiagMeminfo)(unsafe.Pointer(&raw[i].Data[cursor+unix.SizeofNlAttr : cursor+int(nlAttr.Len)][0]))
-			case INET_DIAG_INFO:
-				si.TCPInfo = (*TCPInfo)(unsafe.Pointer(&raw[i].Data[cursor+unix.SizeofNlAttr : cursor+int(nlAttr.Len)][0]))
-			case INET_DIAG_VEGASINFO:
-				si.VegasInfo = (*TCPVegasInfo)(unsafe.Pointer(&raw[i].Data[cursor+unix.SizeofNlAttr : cursor+int(nlAttr.Len)][0]))
-			case INET_DIAG_CONG:
-				si.CONG = make([]byte, 0)
-				si.CONG = append(si.CONG, raw[i].Data[cursor+unix.SizeofNlAttr:cursor+int(nlAttr.Len)]...)
-			case INET_DIAG_TOS:
-				// tos := *(*uint8)(unsafe.Pointer(&raw[i].Data[cursor+unix.SizeofNlAttr : cursor+int(nlAttr.Len)][0]))
-			case INET_DIAG_TCLASS:
-				// tclass := *(*uint8)(unsafe.Pointer(&raw[i].Data[cursor+unix.SizeofNlAttr : cursor+int(nlAttr.Len)][0]))
-			case INET_DIAG_SKMEMINFO:
-				if nlAttr.Len > 4 {
-					si.Meminfo = make([]uint32, 0, 8)
-					for j := cursor + unix.SizeofNlAttr; j < cursor+int(nlAttr.Len); j = j + 4 {
-						si.Meminfo = append(si.Meminfo, *(*uint32)(unsafe.Pointer(&raw[i].Data[j : j+4][0])))
-					}
+		case INET_DIAG_SKMEMINFO:
+			if len(payload) > 0 {
+				si.Meminfo = make([]uint32, 0, SK_MEMINFO_VARS)
+				for j := 0; j+4 <= len(payload); j += 4 {
+					si.Meminfo = append(si.Meminfo, *(*uint32)(unsafe.Pointer(&payload[j])))
 				}
-			case INET_DIAG_SHUTDOWN:
-				// shutdown := *(*uint8)(unsafe.Pointer(&raw[i].Data[cursor+unix.SizeofNlAttr : cursor+int(nlAttr.Len)][0]))
-			default:
 			}
-			cursor += int(nlAttr.Len)
-		}
-		if FlagProcess {
-			si.SetUpRelation()
+		case INET_DIAG_SHUTDOWN:
+			if len(payload) >= 1 {
+				si.Shutdown = payload[0]
+			}
+		case INET_DIAG_SKV6ONLY:
+			if len(payload) >= 1 {
+				si.V6Only = new(bool)
+				*si.V6Only = payload[0] != 0
+			}
+		case INET_DIAG_LOCALS:
+			si.LocalAddrs = parseSockaddrList(payload)
+		case INET_DIAG_PEERS:
+			si.RemoteAddrs = parseSockaddrList(payload)
+		case INET_DIAG_MARK:
+			if len(payload) >= 4 {
+				si.Mark = *(*uint32)(unsafe.Pointer(&payload[0]))
+			}
+		case INET_DIAG_CLASS_ID:
+			if len(payload) >= 4 {
+				si.ClassID = *(*uint32)(unsafe.Pointer(&payload[0]))
+			}
+		case INET_DIAG_MD5SIG:
+			for j := 0; j+SizeOfTCPDiagMD5Sig <= len(payload); j += SizeOfTCPDiagMD5Sig {
+				si.MD5Sig = append(si.MD5Sig, *(*TCPDiagMD5Sig)(unsafe.Pointer(&payload[j])))
+			}
+		case INET_DIAG_ULP_INFO:
+			parseULPInfo(payload, si)
+		case INET_DIAG_CGROUP_ID:
+			if len(payload) >= 8 {
+				si.CgroupID = *(*uint64)(unsafe.Pointer(&payload[0]))
+			}
+		case INET_DIAG_SOCKOPT:
+			if len(payload) >= 2 {
+				si.SockOpt = *(*uint16)(unsafe.Pointer(&payload[0]))
+			}
+		default:
 		}
-		SocketInfoChan <- *si
+		cursor += int(nlAttr.Len+unix.NLA_ALIGNTO-1) & ^(unix.NLA_ALIGNTO - 1)
+	}
+	if si.Protocal == ProtocalSCTP {
+		si.fixSCTPState()
 	}
 	return nil
 }
 
-func RecvInetDiagMsgAll(skfd int) {
-	defer func() {
-		SocketInfoChan <- SocketInfo{IsEnd: true}
-	}()
-	for {
-		if err := RecvInetDiagMsgMulti(skfd); err != nil {
-			if err == ErrorDone {
-				return
-			}
-			continue
+// GenericInetRead reads sockets according to the package level filters and
+// flags. Concurrent callers should use a Client of their own instead.
+func GenericInetRead(protocal, af int) (sis []SocketInfo, err error) {
+	c := newClientFromFlags()
+	defer c.Close()
+	if sis, err = c.InetRead(protocal, af); err != nil && !errors.Is(err, ErrTruncatedMessage) {
+		return nil, err
+	}
+	return sis, err
+}
+
+// parseULPInfo reads the nested INET_DIAG_ULP_INFO attribute, only kTLS has
+// details worth decoding.
+func parseULPInfo(data []byte, si *SocketInfo) {
+	var (
+		cursor int
+		nlAttr unix.NlAttr
+	)
+	for cursor+unix.SizeofNlAttr <= len(data) {
+		nlAttr = *(*unix.NlAttr)(unsafe.Pointer(&data[cursor]))
+		if nlAttr.Len < unix.SizeofNlAttr || cursor+int(nlAttr.Len) > len(data) {
+			break
 		}
+		payload := data[cursor+unix.SizeofNlAttr : cursor+int(nlAttr.Len)]
+		switch nlAttr.Type &^ (unix.NLA_F_NESTED | unix.NLA_F_NET_BYTEORDER) {
+		case INET_ULP_INFO_NAME:
+			si.ULPName = string(bytes.TrimRight(payload, "\x00"))
+		case INET_ULP_INFO_TLS:
+			si.TLSInfo = parseTLSInfo(payload)
+		}
+		cursor += int(nlAttr.Len+unix.NLA_ALIGNTO-1) & ^(unix.NLA_ALIGNTO - 1)
 	}
 }
 
-func GenericInetRead(protocal, af int) (sis map[uint32]SocketInfo, err error) {
+func parseTLSInfo(data []byte) *TLSInfo {
 	var (
-		ipproto uint8
-		exts    uint8
-		skfd    int
+		cursor int
+		nlAttr unix.NlAttr
 	)
-	switch protocal {
-	case ProtocalTCP:
-		ipproto = unix.IPPROTO_TCP
-		if FlagInfo {
-			exts |= 1 << (INET_DIAG_INFO - 1)
-			exts |= 1 << (INET_DIAG_VEGASINFO - 1)
-			exts |= 1 << (INET_DIAG_CONG - 1)
+	info := new(TLSInfo)
+	for cursor+unix.SizeofNlAttr <= len(data) {
+		nlAttr = *(*unix.NlAttr)(unsafe.Pointer(&data[cursor]))
+		if nlAttr.Len < unix.SizeofNlAttr || cursor+int(nlAttr.Len) > len(data) {
+			break
 		}
-	case ProtocalUDP:
-		ipproto = unix.IPPROTO_UDP
-	case ProtocalRAW:
-		ipproto = unix.IPPROTO_RAW
-	default:
-		return nil, fmt.Errorf("invalid protocal:[%d]", protocal)
+		payload := data[cursor+unix.SizeofNlAttr : cursor+int(nlAttr.Len)]
+		var value uint16
+		if len(payload) >= 2 {
+			value = *(*uint16)(unsafe.Pointer(&payload[0]))
+		}
+		switch nlAttr.Type {
+		case TLS_INFO_VERSION:
+			info.Version = value
+		case TLS_INFO_CIPHER:
+			info.Cipher = value
+		case TLS_INFO_TXCONF:
+			info.TxConf = value
+		case TLS_INFO_RXCONF:
+			info.RxConf = value
+		case TLS_INFO_ZC_RO_TX:
+			info.ZcRoTx = true
+		case TLS_INFO_RX_NO_PAD:
+			info.RxNoPad = true
+		}
+		cursor += int(nlAttr.Len+unix.NLA_ALIGNTO-1) & ^(unix.NLA_ALIGNTO - 1)
 	}
-	if FlagMemory {
-		exts |= 1 << (INET_DIAG_SKMEMINFO - 1)
+	return info
+}
+
+// inetDiagExts selects the attributes that are only sent when asked for,
+// SHUTDOWN, SKV6ONLY, MARK, CGROUP_ID and SOCKOPT always come along.
+func inetDiagExts(protocal int, info, memory, extended bool) (exts uint8) {
+	if info && protocal == ProtocalTCP {
+		exts |= 1 << (INET_DIAG_INFO - 1)
+		exts |= 1 << (INET_DIAG_VEGASINFO - 1)
+		exts |= 1 << (INET_DIAG_CONG - 1)
+	}
+	if extended {
+		// TCLASS also asks for CLASS_ID
+		exts |= 1 << (INET_DIAG_TOS - 1)
+		exts |= 1 << (INET_DIAG_TCLASS - 1)
 	}
-	if skfd, err = SendInetDiagMsg(uint8(af), ipproto, exts, SsFilter); err != nil {
-		goto readProc
+	if info && (protocal == ProtocalSCTP || protocal == ProtocalMPTCP) {
+		exts |= 1 << (INET_DIAG_INFO - 1)
+	}
+	if memory {
+		exts |= 1 << (INET_DIAG_MEMINFO - 1)
+		exts |= 1 << (INET_DIAG_SKMEMINFO - 1)
 	}
-	defer unix.Close(skfd)
+	return exts
+}
 
-	sis = make(map[uint32]SocketInfo)
-	go RecvInetDiagMsgAll(skfd)
-	for si := range SocketInfoChan {
-		if si.IsEnd {
-			return sis, nil
-		}
-		sis[si.Inode] = si
+func parseProcAddrPort(raw string) (sa SockAddr, err error) {
+	stringBuff := strings.Split(raw, ":")
+	if len(stringBuff) != 2 {
+		return sa, fmt.Errorf("invalid address:[%s]", raw)
+	}
+	addr, err := ParseProcHexAddr(stringBuff[0])
+	if err != nil {
+		return sa, err
+	}
+	port, err := strconv.ParseUint(stringBuff[1], 16, 16)
+	if err != nil {
+		return sa, err
 	}
+	sa.AddrPort = netip.AddrPortFrom(addr, uint16(port))
+	return sa, nil
+}
 
-readProc:
+func (c *Client) inetReadProc(protocal, af int, fn func(si *SocketInfo) bool) (err error) {
 	var (
 		procPath    string
 		file        *os.File
@@ -383,13 +664,13 @@ readProc:
 		stringBuff  []string
 		tempInt64   int64
 	)
-	sis = make(map[uint32]SocketInfo)
-
 	switch protocal {
 	case ProtocalTCP:
 		procPath = "TCP"
 	case ProtocalUDP:
 		procPath = "UDP"
+	case ProtocalUDPLite:
+		procPath = "UDPLITE"
 	case ProtocalRAW:
 		procPath = "RAW"
 	}
@@ -399,72 +680,45 @@ readProc:
 	case unix.AF_INET6:
 		procPath += "6"
 	}
-	if file, err = os.Open(procFilePath[procPath]); err != nil {
-		return nil, err
+	if file, err = c.openProc(procPath); err != nil {
+		return err
 	}
 	defer file.Close()
 
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
-		if err = scanner.Err(); err != nil {
-			return sis, err
-		}
 		line = scanner.Text()
 		fields = strings.Fields(line)
-		if fields[0] == "sl" {
+		// sl through the kernel address of the socket
+		if len(fields) < 12 || fields[0] == "sl" {
 			continue
 		}
 		si := NewSocketInfo()
+		si.Protocal = protocal
 		// Local address
 		fieldsIndex = 1
-		stringBuff = strings.Split(fields[fieldsIndex], ":")
-		switch af {
-		case unix.AF_INET:
-			si.LocalAddr.Host, err = IPv4HexToString(stringBuff[0])
-		case unix.AF_INET6:
-			si.LocalAddr.Host, err = IPv6HexToString(stringBuff[0])
-		}
-		if err != nil {
+		if si.LocalAddr, err = parseProcAddrPort(fields[fieldsIndex]); err != nil {
 			continue
 		}
-		if tempInt64, err = strconv.ParseInt(stringBuff[1], 16, 64); err != nil {
-			continue
-		}
-		si.LocalAddr.Port = fmt.Sprintf("%d", tempInt64)
-		if MaxLocalAddrLength < len(si.LocalAddr.String()) {
-			MaxLocalAddrLength = len(si.LocalAddr.String())
-		}
 		fieldsIndex++
 		// Remote address
-		stringBuff = strings.Split(fields[fieldsIndex], ":")
-		switch af {
-		case unix.AF_INET:
-			si.RemoteAddr.Host, err = IPv4HexToString(stringBuff[0])
-		case unix.AF_INET6:
-			si.RemoteAddr.Host, err = IPv6HexToString(stringBuff[0])
-		}
-		if err != nil {
+		if si.RemoteAddr, err = parseProcAddrPort(fields[fieldsIndex]); err != nil {
 			continue
 		}
-		if tempInt64, err = strconv.ParseInt(stringBuff[1], 16, 64); err != nil {
-			continue
-		}
-		si.RemoteAddr.Port = fmt.Sprintf("%d", tempInt64)
-		if MaxRemoteAddrLength < len(si.RemoteAddr.String()) {
-			MaxRemoteAddrLength = len(si.RemoteAddr.String())
-		}
 		fieldsIndex++
 		// Status
 		if tempInt64, err = strconv.ParseInt(fields[fieldsIndex], 16, 32); err != nil {
 			continue
 		}
 		si.Status = uint8(tempInt64)
-		if SsFilter&(1<<si.Status) == 0 {
+		if c.States&(1<<si.Status) == 0 {
 			continue
 		}
 		fieldsIndex++
 		// TxQueue:RxQueue
-		stringBuff = strings.Split(fields[fieldsIndex], ":")
+		if stringBuff = strings.Split(fields[fieldsIndex], ":"); len(stringBuff) != 2 {
+			continue
+		}
 		if tempInt64, err = strconv.ParseInt(stringBuff[0], 16, 64); err != nil {
 			continue
 		}
@@ -475,7 +729,9 @@ readProc:
 		si.RxQueue = uint32(tempInt64)
 		fieldsIndex++
 		// Timer:TmWhen
-		stringBuff = strings.Split(fields[fieldsIndex], ":")
+		if stringBuff = strings.Split(fields[fieldsIndex], ":"); len(stringBuff) != 2 {
+			continue
+		}
 		if tempInt64, err = strconv.ParseInt(stringBuff[0], 16, 32); err != nil {
 			continue
 		}
@@ -517,7 +773,7 @@ readProc:
 		}
 		switch protocal {
 		case ProtocalTCP:
-			if len(fields) > 12 {
+			if len(fields) > 16 {
 				fieldsIndex++
 				if si.RTO, err = strconv.ParseFloat(fields[fieldsIndex], 64); err != nil {
 					continue
@@ -556,7 +812,7 @@ readProc:
 			if si.Timer != 1 {
 				si.Retransmit = si.Probes
 			}
-		case ProtocalUDP, ProtocalRAW:
+		case ProtocalUDP, ProtocalUDPLite, ProtocalRAW:
 			fieldsIndex++
 			if si.Drops, err = strconv.Atoi(fields[fieldsIndex]); err != nil {
 				continue
@@ -565,12 +821,18 @@ readProc:
 		if len(fields) > 17 {
 			si.Opt = fields[17:]
 		}
-		if FlagProcess {
+		// the kernel can not filter for us when reading proc files
+		if c.Filter != nil && !c.Filter.Match(si) {
+			continue
+		}
+		if c.Process {
 			si.SetUpRelation()
 		}
-		sis[si.Inode] = *si
+		if !fn(si) {
+			return nil
+		}
 	}
-	return
+	return scanner.Err()
 }
 
 type UnixDiagReq struct {
@@ -598,8 +860,8 @@ type UnixDiagMessage struct {
 }
 
 type UnixDiagVFS struct {
-	Dev uint32
 	Ino uint32
+	Dev uint32
 }
 
 type UnixDiagRQlen struct {
@@ -607,143 +869,116 @@ type UnixDiagRQlen struct {
 	WQ uint32
 }
 
-// Make sure the caller of the function will close skfd
-func SendUnixDiagMsg(states uint32, show uint32) (skfd int, err error) {
-	if skfd, err = unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW, unix.NETLINK_SOCK_DIAG); err != nil {
-		return -1, err
-	}
-	sockAddrNl.Family = unix.AF_NETLINK
-	unDiagReq.Header.Type = SOCK_DIAG_BY_FAMILY
-	unDiagReq.Header.Flags = unix.NLM_F_DUMP | unix.NLM_F_REQUEST
-	unDiagReq.Request.SdiagFamily = unix.AF_UNIX
-	unDiagReq.Request.UdiagStates = states
-	unDiagReq.Request.UdiagShow = show
-	unDiagReq.Header.Len = uint32(unsafe.Sizeof(unDiagReq))
-	*(*UnixDiagRequest)(unsafe.Pointer(&unDiagRequestBuffer[0])) = unDiagReq
-	if err = unix.Sendmsg(skfd, unDiagRequestBuffer, nil, &sockAddrNl, 0); err != nil {
-		return -1, err
-	}
-	return skfd, nil
+func newUnixDiagRequest(states uint32, show uint32) []byte {
+	var req UnixDiagRequest
+	req.Header.Type = SOCK_DIAG_BY_FAMILY
+	req.Header.Flags = unix.NLM_F_DUMP | unix.NLM_F_REQUEST
+	req.Request.SdiagFamily = unix.AF_UNIX
+	req.Request.UdiagStates = states
+	req.Request.UdiagShow = show
+	req.Header.Len = uint32(unsafe.Sizeof(req))
+	request := make([]byte, SizeOfUnixDiagRequest)
+	*(*UnixDiagRequest)(unsafe.Pointer(&request[0])) = req
+	return request
 }
 
-func RecvUnixDiagMsgMulti(skfd int) (err error) {
-	var cursor int
-
-	for {
-		if bytesCounter, _, _, _, err = unix.Recvmsg(skfd, sockDiagMsgBuffer, nil, unix.MSG_PEEK); err != nil {
-			return err
-		}
-		if bytesCounter < len(sockDiagMsgBuffer) {
+func parseUnixDiagMsg(data []byte, si *SocketInfo) error {
+	var (
+		cursor int
+		nlAttr unix.NlAttr
+	)
+	if len(data) < SizeOfUnixDiagMsg {
+		return fmt.Errorf("%w:[unix_diag, %d bytes]", ErrTruncatedMessage, len(data))
+	}
+	unDiagMsg := *(*UnixDiagMessage)(unsafe.Pointer(&data[0]))
+	si.Inode = unDiagMsg.UdiagIno
+	si.LocalAddr.ID = unDiagMsg.UdiagIno
+	si.Status = unDiagMsg.UdiagState
+	si.Type = unDiagMsg.UdiagType
+	si.SK = uint64(unDiagMsg.UdiagCookie[1])<<32 | uint64(unDiagMsg.UdiagCookie[0])
+	cursor = SizeOfUnixDiagMsg
+	for cursor+unix.SizeofNlAttr <= len(data) {
+		nlAttr = *(*unix.NlAttr)(unsafe.Pointer(&data[cursor]))
+		if nlAttr.Len < unix.SizeofNlAttr || cursor+int(nlAttr.Len) > len(data) {
 			break
 		}
-		sockDiagMsgBuffer = make([]byte, 2*len(sockDiagMsgBuffer))
-	}
-	if bytesCounter, _, _, _, err = unix.Recvmsg(skfd, sockDiagMsgBuffer, nil, 0); err != nil {
-		return err
-	}
-	raw, err := syscall.ParseNetlinkMessage(sockDiagMsgBuffer[:bytesCounter])
-	if err != nil {
-		return err
-	}
-	si := NewSocketInfo()
-	for i := range raw {
-		si.Reset()
-		if raw[i].Header.Type == unix.NLMSG_DONE {
-			return ErrorDone
-		}
-		unDiagMsg = *(*UnixDiagMessage)(unsafe.Pointer(&raw[i].Data[:SizeOfUnixDiagMsg][0]))
-		si.Inode = unDiagMsg.UdiagIno
-		si.LocalAddr.Port = fmt.Sprintf("%d", unDiagMsg.UdiagIno)
-		si.Status = unDiagMsg.UdiagState
-		si.Type = unDiagMsg.UdiagType
-		si.SK = uint64(unDiagMsg.UdiagCookie[1])<<32 | uint64(unDiagMsg.UdiagCookie[0])
-		cursor = SizeOfUnixDiagMsg
-		for cursor+4 < len(raw[i].Data) {
-			for raw[i].Data[cursor] == byte(0) {
-				cursor++
+		payload := data[cursor+unix.SizeofNlAttr : cursor+int(nlAttr.Len)]
+		switch nlAttr.Type {
+		case UNIX_DIAG_NAME:
+			si.UnixName = ParseUnixName(payload)
+			si.LocalAddr.Name = si.UnixName.String()
+		case UNIX_DIAG_VFS:
+			if len(payload) >= SizeOfUnixDiagVFS {
+				si.UnixVFS = new(UnixDiagVFS)
+				*si.UnixVFS = *(*UnixDiagVFS)(unsafe.Pointer(&payload[0]))
 			}
-			nlAttr = *(*unix.NlAttr)(unsafe.Pointer(&raw[i].Data[cursor : cursor+unix.SizeofNlAttr][0]))
-			switch nlAttr.Type {
-			case UNIX_DIAG_NAME:
-				si.LocalAddr.Host = string(raw[i].Data[cursor+unix.SizeofNlAttr : cursor+int(nlAttr.Len)])
-				if len(si.LocalAddr.Host) == 0 {
-					si.LocalAddr.Host = "*"
-				}
-				if MaxLocalAddrLength < len(si.LocalAddr.String()) {
-					MaxLocalAddrLength = len(si.LocalAddr.String())
-				}
-			case UNIX_DIAG_VFS:
-				// vfs := *(*UnixDiagVFS)(unsafe.Pointer(&raw[i].Data[cursor+unix.SizeofNlAttr : cursor+int(nlAttr.Len)][0]))
-			case UNIX_DIAG_PEER:
-				si.RemoteAddr.Host = "*"
-				si.RemoteAddr.Port = fmt.Sprintf("%d", *(*uint32)(unsafe.Pointer(&raw[i].Data[cursor+unix.SizeofNlAttr : cursor+int(nlAttr.Len)][0])))
-				if MaxRemoteAddrLength < len(si.RemoteAddr.String()) {
-					MaxRemoteAddrLength = len(si.RemoteAddr.String())
-				}
-			case UNIX_DIAG_ICONS:
-				// if nlAttr.Len > 4 {
-				// 	icons := make([]uint32, 0)
-				// 	for j := cursor + unix.SizeofNlAttr; j < cursor+int(nlAttr.Len); j = j + 4 {
-				// 		icons = append(icons, *(*uint32)(unsafe.Pointer(&raw[i].Data[j : j+4][0])))
-				// 	}
-				// }
-			case UNIX_DIAG_RQLEN:
-				unDiagRQlen = *(*UnixDiagRQlen)(unsafe.Pointer(&raw[i].Data[cursor+unix.SizeofNlAttr : cursor+int(nlAttr.Len)][0]))
+		case UNIX_DIAG_PEER:
+			if len(payload) >= 4 {
+				si.RemoteAddr.ID = *(*uint32)(unsafe.Pointer(&payload[0]))
+			}
+		case UNIX_DIAG_ICONS:
+			si.UnixIcons = make([]uint32, 0, len(payload)/4)
+			for j := 0; j+4 <= len(payload); j += 4 {
+				si.UnixIcons = append(si.UnixIcons, *(*uint32)(unsafe.Pointer(&payload[j])))
+			}
+		case UNIX_DIAG_RQLEN:
+			if len(payload) >= SizeOfUnixDiagRQlen {
+				unDiagRQlen := *(*UnixDiagRQlen)(unsafe.Pointer(&payload[0]))
 				si.RxQueue = unDiagRQlen.RQ
 				si.TxQueue = unDiagRQlen.WQ
-			case UNIX_DIAG_MEMINFO:
-				if nlAttr.Len > 4 {
-					si.Meminfo = make([]uint32, 0, 8)
-					for j := cursor + unix.SizeofNlAttr; j < cursor+int(nlAttr.Len); j = j + 4 {
-						si.Meminfo = append(si.Meminfo, *(*uint32)(unsafe.Pointer(&raw[i].Data[j : j+4][0])))
-					}
+			}
+		case UNIX_DIAG_MEMINFO:
+			if len(payload) > 0 {
+				si.Meminfo = make([]uint32, 0, SK_MEMINFO_VARS)
+				for j := 0; j+4 <= len(payload); j += 4 {
+					si.Meminfo = append(si.Meminfo, *(*uint32)(unsafe.Pointer(&payload[j])))
 				}
-			case UNIX_DIAG_SHUTDOWN:
-				// shutdown := *(*uint8)(unsafe.Pointer(&raw[i].Data[cursor+unix.SizeofNlAttr : cursor+int(nlAttr.Len)][0]))
-			default:
-				fmt.Println("invalid NlAttr Type")
 			}
-			cursor += int(nlAttr.Len)
-		}
-		if FlagProcess {
-			si.SetUpRelation()
+		case UNIX_DIAG_SHUTDOWN:
+			if len(payload) >= 1 {
+				si.Shutdown = payload[0]
+			}
 		}
-		SocketInfoChan <- *si
+		cursor += int(nlAttr.Len+unix.NLA_ALIGNTO-1) & ^(unix.NLA_ALIGNTO - 1)
 	}
 	return nil
 }
 
-func RecvUnixDiagMsgAll(skfd int) {
-	defer func() {
-		SocketInfoChan <- SocketInfo{IsEnd: true}
-	}()
-	for {
-		if err := RecvUnixDiagMsgMulti(skfd); err != nil {
-			if err == ErrorDone {
-				return
-			}
+// resolveUnixPeers fills in the path and the processes of the peer of every
+// connected unix socket, as far as the peer is among sis or has owners.
+func resolveUnixPeers(sis []SocketInfo, process bool) {
+	names := make(map[uint32]string, len(sis))
+	for i := range sis {
+		if len(sis[i].LocalAddr.Name) > 0 {
+			names[sis[i].Inode] = sis[i].LocalAddr.Name
+		}
+	}
+	for i := range sis {
+		peer := sis[i].RemoteAddr.ID
+		if peer == 0 {
 			continue
 		}
+		if len(sis[i].RemoteAddr.Name) == 0 {
+			sis[i].RemoteAddr.Name = names[peer]
+		}
+		if process {
+			sis[i].PeerOwners = GlobalSocketOwners[peer]
+		}
 	}
 }
 
-func GenericUnixRead() (sis map[uint32]SocketInfo, err error) {
-	skfd, err := SendUnixDiagMsg(SsFilter,
-		UDIAG_SHOW_NAME|UDIAG_SHOW_VFS|UDIAG_SHOW_PEER|UDIAG_SHOW_ICONS|UDIAG_SHOW_RQLEN|UDIAG_SHOW_MEMINFO)
-	if err != nil {
-		goto readProc
-	}
-	defer unix.Close(skfd)
-	sis = make(map[uint32]SocketInfo)
-	go RecvUnixDiagMsgAll(skfd)
-	for si := range SocketInfoChan {
-		if si.IsEnd {
-			return sis, nil
-		}
-		sis[si.Inode] = si
+// GenericUnixRead reads unix sockets according to the package level filters
+// and flags. Concurrent callers should use a Client of their own instead.
+func GenericUnixRead() (sis []SocketInfo, err error) {
+	c := newClientFromFlags()
+	defer c.Close()
+	if sis, err = c.UnixRead(); err != nil && !errors.Is(err, ErrTruncatedMessage) {
+		return nil, err
 	}
+	return sis, err
+}
 
-readProc:
+func (c *Client) unixReadProc(fn func(si *SocketInfo) bool) (err error) {
 	// In this way, so much information cannot get.
 	var (
 		line        string
@@ -751,18 +986,14 @@ readProc:
 		fieldsIndex int
 		flag        int64
 	)
-	sis = make(map[uint32]SocketInfo)
-	file, err := os.Open(procFilePath["Unix"])
+	file, err := c.openProc("Unix")
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer file.Close()
 	scanner := bufio.NewScanner(file)
 	var tempInt64 int64
 	for scanner.Scan() {
-		if err = scanner.Err(); err != nil {
-			return sis, err
-		}
 		line = scanner.Text()
 		fields = strings.Fields(line)
 		if len(fields) < 7 {
@@ -772,16 +1003,12 @@ readProc:
 			continue
 		}
 		si := NewSocketInfo()
+		si.Protocal = ProtocalUnix
 		// Num: the kernel table slot number.
 		fieldsIndex = 0
 		if si.SK, err = strconv.ParseUint(strings.Replace(fields[fieldsIndex], ":", "", -1), 16, 64); err != nil {
 			continue
 		}
-		si.RemoteAddr.Host = "*"
-		si.RemoteAddr.Port = "Unknown"
-		if MaxRemoteAddrLength < len(si.RemoteAddr.String()) {
-			MaxRemoteAddrLength = len(si.RemoteAddr.String())
-		}
 		fieldsIndex++
 		// RefCount: the number of users of the socket.
 		si.RxQueue = 0
@@ -806,10 +1033,12 @@ readProc:
 		}
 		if flag&(1<<16) != 0 {
 			si.Status = SsLISTEN
-		} else {
+		} else if tempInt64 >= 1 && int(tempInt64) <= len(UnixSstate) {
 			si.Status = UnixSstate[int(tempInt64)-1]
+		} else {
+			continue
 		}
-		if SsFilter&(1<<si.Status) == 0 {
+		if c.States&(1<<si.Status) == 0 {
 			continue
 		}
 		fieldsIndex++
@@ -818,85 +1047,179 @@ readProc:
 			continue
 		}
 		si.Inode = uint32(tempInt64)
-		si.LocalAddr.Port = fmt.Sprintf("%d", si.Inode)
+		si.LocalAddr.ID = si.Inode
 		// Path: the bound path (if any) of the socket.
 		// Sockets in the abstract namespace are included in the list, and are shown with a Path that commences with the character '@'.
-		if len(fields) > 7 {
-			fieldsIndex++
-			si.LocalAddr.Host = fields[fieldsIndex]
-		} else {
-			si.LocalAddr.Host = "*"
+		// The path is printed as is, so it may contain spaces.
+		if path := procUnixPath(line); len(path) > 0 {
+			if path[0] == '@' {
+				// NULs inside the name are shown as '@' as well
+				si.UnixName = UnixName{Kind: UnixNameAbstract, Name: path[1:]}
+			} else {
+				si.UnixName = UnixName{Kind: UnixNamePath, Name: path}
+			}
+			si.LocalAddr.Name = si.UnixName.String()
 		}
-		if MaxLocalAddrLength < len(si.LocalAddr.String()) {
-			MaxLocalAddrLength = len(si.LocalAddr.String())
+		if c.Filter != nil && !c.Filter.Match(si) {
+			continue
 		}
-		if FlagProcess {
+		if c.Process {
 			si.SetUpRelation()
 		}
-		sis[si.Inode] = *si
+		if !fn(si) {
+			return nil
+		}
 	}
-	return sis, nil
+	return scanner.Err()
 }
 
-func GetSocketCount(fields []string) (int, error) {
-	for i := range fields {
-		if fields[i] == "inuse" {
-			return strconv.Atoi(fields[i+1])
+// procUnixPath returns what follows the 7 fixed fields of a /proc/net/unix
+// line, which is separated from them by a single space.
+func procUnixPath(line string) string {
+	rest := line
+	for i := 0; i < 7; i++ {
+		rest = strings.TrimLeft(rest, " ")
+		j := strings.IndexByte(rest, ' ')
+		if j < 0 {
+			return ""
 		}
+		rest = rest[j:]
 	}
-	return 0, nil
+	return strings.TrimPrefix(rest, " ")
 }
 
-// IPv6:versionFlag = true; IPv4:versionFlag = false
-func GenericReadSockstat() (summary map[string]map[string]int, err error) {
-	summary = make(map[string]map[string]int)
-	for _, pf := range SummaryPF {
-		summary[pf] = make(map[string]int)
+// SockstatCounters is one line of /proc/net/sockstat, fields a protocol
+// does not report stay zero. Mem is in pages, Memory of FRAG in bytes.
+type SockstatCounters struct {
+	InUse  int
+	Orphan int
+	TW     int
+	Alloc  int
+	Mem    int
+	Memory int
+}
+
+type SockstatFamily struct {
+	TCP     SockstatCounters
+	UDP     SockstatCounters
+	UDPLite SockstatCounters
+	RAW     SockstatCounters
+	FRAG    SockstatCounters
+}
+
+// SockstatSummary is what ss -s shows. The counters come from the sockstat
+// files, TCPStates counts the TCP sockets of a sock_diag dump by Ss* state.
+type SockstatSummary struct {
+	Used      int // sockets of all families
+	IPv4      SockstatFamily
+	IPv6      SockstatFamily
+	TCPStates [SsMAX]int
+	TCPTotal  int
+}
+
+func parseSockstatCounters(fields []string) (counters SockstatCounters, err error) {
+	for i := 0; i+1 < len(fields); i += 2 {
+		var value int
+		if value, err = strconv.Atoi(fields[i+1]); err != nil {
+			return counters, fmt.Errorf("invalid sockstat value:[%s]", fields[i+1])
+		}
+		switch fields[i] {
+		case "inuse":
+			counters.InUse = value
+		case "orphan":
+			counters.Orphan = value
+		case "tw":
+			counters.TW = value
+		case "alloc":
+			counters.Alloc = value
+		case "mem":
+			counters.Mem = value
+		case "memory":
+			counters.Memory = value
+		}
 	}
+	return counters, nil
+}
 
-	var file *os.File
-	for _, v := range []string{"sockstat4", "sockstat6"} {
-		if file, err = os.Open(procFilePath[v]); err != nil {
+// ReadSockstat reads the sockstat files of the namespace of c and counts the
+// TCP sockets by state, only the ones matching c.States and c.Filter.
+func (c *Client) ReadSockstat() (summary *SockstatSummary, err error) {
+	summary = new(SockstatSummary)
+	for _, key := range []string{"sockstat4", "sockstat6"} {
+		file, err := c.openProc(key)
+		if err != nil {
 			return nil, err
 		}
-		defer file.Close()
 		scanner := bufio.NewScanner(file)
 		for scanner.Scan() {
-			if err = scanner.Err(); err != nil {
-				return nil, err
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 1 {
+				continue
 			}
-			line := scanner.Text()
-			fields := strings.Fields(line)
-			switch fields[0] {
-			case "sockets:":
+			if fields[0] == "sockets:" {
+				if len(fields) >= 3 && fields[1] == "used" {
+					summary.Used, _ = strconv.Atoi(fields[2])
+				}
 				continue
+			}
+			var counters *SockstatCounters
+			switch fields[0] {
 			case "TCP:":
-				summary["TCP"][IPv4String], err = GetSocketCount(fields[1:])
+				counters = &summary.IPv4.TCP
 			case "TCP6:":
-				summary["TCP"][IPv6String], err = GetSocketCount(fields[1:])
+				counters = &summary.IPv6.TCP
 			case "UDP:":
-				summary["UDP"][IPv4String], err = GetSocketCount(fields[1:])
+				counters = &summary.IPv4.UDP
 			case "UDP6:":
-				summary["UDP"][IPv6String], err = GetSocketCount(fields[1:])
+				counters = &summary.IPv6.UDP
 			case "UDPLITE:":
-				summary["UDPLITE"][IPv4String], err = GetSocketCount(fields[1:])
+				counters = &summary.IPv4.UDPLite
 			case "UDPLITE6:":
-				summary["UDPLITE"][IPv6String], err = GetSocketCount(fields[1:])
+				counters = &summary.IPv6.UDPLite
 			case "RAW:":
-				summary["RAW"][IPv4String], err = GetSocketCount(fields[1:])
+				counters = &summary.IPv4.RAW
 			case "RAW6:":
-				summary["RAW"][IPv6String], err = GetSocketCount(fields[1:])
+				counters = &summary.IPv6.RAW
 			case "FRAG:":
-				summary["FRAG"][IPv4String], err = GetSocketCount(fields[1:])
+				counters = &summary.IPv4.FRAG
 			case "FRAG6:":
-				summary["FRAG"][IPv6String], err = GetSocketCount(fields[1:])
+				counters = &summary.IPv6.FRAG
 			default:
 				continue
 			}
-			if err != nil {
+			if *counters, err = parseSockstatCounters(fields[1:]); err != nil {
+				file.Close()
 				return nil, err
 			}
 		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, af := range []int{unix.AF_INET, unix.AF_INET6} {
+		err = c.ForEachSocket(SocketQuery{Protocal: ProtocalTCP, Af: af}, func(si *SocketInfo) bool {
+			if int(si.Status) < len(summary.TCPStates) {
+				summary.TCPStates[si.Status]++
+			}
+			summary.TCPTotal++
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
 	}
 	return summary, nil
 }
+
+// GenericReadSockstat reads the summary of the namespace in NetNamespace,
+// through DiagTransport and below ProcRoot like the other Generic readers.
+func GenericReadSockstat() (*SockstatSummary, error) {
+	c := NewClient()
+	c.NetNS = NetNamespace
+	c.Transport = DiagTransport
+	defer c.Close()
+	return c.ReadSockstat()
+}
@@ -75,7 +75,12 @@ type MemoryInfo struct {
 }
 
 func (mi *MemoryInfo) Get() error {
-	fd, err := os.Open(ProcRoot + "/meminfo")
+	return mi.GetFrom(ProcRoot)
+}
+
+// GetFrom reads meminfo of the proc mounted at procRoot.
+func (mi *MemoryInfo) GetFrom(procRoot string) error {
+	fd, err := os.Open(procRoot + "/meminfo")
 	if err != nil {
 		return err
 	}
@@ -247,7 +252,12 @@ type SystemStat struct {
 }
 
 func (ss *SystemStat) Get() (err error) {
-	fd, err := os.Open(ProcRoot + "/stat")
+	return ss.GetFrom(ProcRoot)
+}
+
+// GetFrom reads stat of the proc mounted at procRoot.
+func (ss *SystemStat) GetFrom(procRoot string) (err error) {
+	fd, err := os.Open(procRoot + "/stat")
 	if err != nil {
 		return err
 	}
@@ -332,7 +342,12 @@ type Uptime struct {
 }
 
 func (ut *Uptime) Get() error {
-	raw, err := ioutil.ReadFile(ProcRoot + "/uptime")
+	return ut.GetFrom(ProcRoot)
+}
+
+// GetFrom reads uptime of the proc mounted at procRoot.
+func (ut *Uptime) GetFrom(procRoot string) error {
+	raw, err := ioutil.ReadFile(procRoot + "/uptime")
 	if err != nil {
 		return err
 	}
@@ -357,7 +372,12 @@ type KernelVersion struct {
 }
 
 func (kv *KernelVersion) Get() error {
-	raw, err := ioutil.ReadFile(ProcRoot + "/version")
+	return kv.GetFrom(ProcRoot)
+}
+
+// GetFrom reads version of the proc mounted at procRoot.
+func (kv *KernelVersion) GetFrom(procRoot string) error {
+	raw, err := ioutil.ReadFile(procRoot + "/version")
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,225 @@
+// +build linux
+
+package psss
+
+import (
+	"unsafe"
+)
+
+// struct tcp_info only ever grew at its end, so the length of INET_DIAG_INFO
+// tells which fields a kernel knows. A field is present when Length is at
+// least the size that ends with it.
+const (
+	SizeOfTCPInfoTotalRetrans  = 104
+	SizeOfTCPInfoPacingRate    = 120
+	SizeOfTCPInfoBytesReceived = 136
+	SizeOfTCPInfoSegsIn        = 144
+	SizeOfTCPInfoMinRtt        = 152
+	SizeOfTCPInfoDataSegsOut   = 160
+	SizeOfTCPInfoDeliveryRate  = 168
+	SizeOfTCPInfoSndbufLimited = 192
+	SizeOfTCPInfoDeliveredCE   = 200
+	SizeOfTCPInfoBytesRetrans  = 216
+	SizeOfTCPInfoReordSeen     = 224
+	SizeOfTCPInfoSndWnd        = 232
+	SizeOfTCPInfoRehash        = 240
+	SizeOfTCPInfo              = SizeOfTCPInfoRehash
+)
+
+// tcpInfo is the kernel layout of struct tcp_info.
+type tcpInfo struct {
+	State           uint8
+	Ca_state        uint8
+	Retransmits     uint8
+	Probes          uint8
+	Backoff         uint8
+	Options         uint8
+	Wscale          uint8 // snd_wscale:4, rcv_wscale:4
+	Flags           uint8 // delivery_rate_app_limited:1, fastopen_client_fail:2
+	Rto             uint32
+	Ato             uint32
+	Snd_mss         uint32
+	Rcv_mss         uint32
+	Unacked         uint32
+	Sacked          uint32
+	Lost            uint32
+	Retrans         uint32
+	Fackets         uint32
+	Last_data_sent  uint32
+	Last_ack_sent   uint32
+	Last_data_recv  uint32
+	Last_ack_recv   uint32
+	Pmtu            uint32
+	Rcv_ssthresh    uint32
+	Rtt             uint32
+	Rttvar          uint32
+	Snd_ssthresh    uint32
+	Snd_cwnd        uint32
+	Advmss          uint32
+	Reordering      uint32
+	Rcv_rtt         uint32
+	Rcv_space       uint32
+	Total_retrans   uint32
+	Pacing_rate     uint64
+	Max_pacing_rate uint64
+	Bytes_acked     uint64
+	Bytes_received  uint64
+	Segs_out        uint32
+	Segs_in         uint32
+	Notsent_bytes   uint32
+	Min_rtt         uint32
+	Data_segs_in    uint32
+	Data_segs_out   uint32
+	Delivery_rate   uint64
+	Busy_time       uint64
+	Rwnd_limited    uint64
+	Sndbuf_limited  uint64
+	Delivered       uint32
+	Delivered_ce    uint32
+	Bytes_sent      uint64
+	Bytes_retrans   uint64
+	Dsack_dups      uint32
+	Reord_seen      uint32
+	Rcv_ooopack     uint32
+	Snd_wnd         uint32
+	Rcv_wnd         uint32
+	Rehash          uint32
+}
+
+type TCPInfo struct {
+	Length int `json:"length"` // bytes of struct tcp_info sent by the kernel
+
+	State                     uint8  `json:"state"`
+	Ca_state                  uint8  `json:"ca_state"`
+	Retransmits               uint8  `json:"retransmits"`
+	Probes                    uint8  `json:"probes"`
+	Backoff                   uint8  `json:"backoff"`
+	Options                   uint8  `json:"options"`
+	Snd_wscale                uint8  `json:"snd_wscale"`
+	Rcv_wscale                uint8  `json:"rcv_wscale"`
+	Delivery_rate_app_limited bool   `json:"delivery_rate_app_limited"` // shown as app_limited
+	Fastopen_client_fail      uint8  `json:"fastopen_client_fail"`
+	Rto                       uint32 `json:"rto"`
+	Ato                       uint32 `json:"ato"`
+	Snd_mss                   uint32 `json:"snd_mss"`
+	Rcv_mss                   uint32 `json:"rcv_mss"`
+	Unacked                   uint32 `json:"unacked"`
+	Sacked                    uint32 `json:"sacked"`
+	Lost                      uint32 `json:"lost"`
+	Retrans                   uint32 `json:"retrans"`
+	Fackets                   uint32 `json:"fackets"`
+	Last_data_sent            uint32 `json:"last_data_sent"`
+	Last_ack_sent             uint32 `json:"last_ack_sent"`
+	Last_data_recv            uint32 `json:"last_data_recv"`
+	Last_ack_recv             uint32 `json:"last_ack_recv"`
+	Pmtu                      uint32 `json:"pmtu"`
+	Rcv_ssthresh              uint32 `json:"rcv_ssthresh"`
+	Rtt                       uint32 `json:"rtt"`
+	Rttvar                    uint32 `json:"rttvar"`
+	Snd_ssthresh              uint32 `json:"snd_ssthresh"`
+	Snd_cwnd                  uint32 `json:"snd_cwnd"`
+	Advmss                    uint32 `json:"advmss"`
+	Reordering                uint32 `json:"reordering"`
+	Rcv_rtt                   uint32 `json:"rcv_rtt"`
+	Rcv_space                 uint32 `json:"rcv_space"`
+	Total_retrans             uint32 `json:"total_retrans"`
+	Pacing_rate               uint64 `json:"pacing_rate"`
+	Max_pacing_rate           uint64 `json:"max_pacing_rate"`
+	Bytes_acked               uint64 `json:"bytes_acked"`    /* RFC4898 tcpEStatsAppHCThruOctetsAcked */
+	Bytes_received            uint64 `json:"bytes_received"` /* RFC4898 tcpEStatsAppHCThruOctetsReceived */
+	Segs_out                  uint32 `json:"segs_out"`       /* RFC4898 tcpEStatsPerfSegsOut */
+	Segs_in                   uint32 `json:"segs_in"`        /* RFC4898 tcpEStatsPerfSegsIn */
+	Notsent_bytes             uint32 `json:"notsent_bytes"`
+	Min_rtt                   uint32 `json:"min_rtt"`
+	Data_segs_in              uint32 `json:"data_segs_in"`  /* RFC4898 tcpEStatsDataSegsIn */
+	Data_segs_out             uint32 `json:"data_segs_out"` /* RFC4898 tcpEStatsDataSegsOut */
+	Delivery_rate             uint64 `json:"delivery_rate"`
+	Busy_time                 uint64 `json:"busy_time"`      /* Time (usec) busy sending data */
+	Rwnd_limited              uint64 `json:"rwnd_limited"`   /* Time (usec) limited by receive window */
+	Sndbuf_limited            uint64 `json:"sndbuf_limited"` /* Time (usec) limited by send buffer */
+	Delivered                 uint32 `json:"delivered"`
+	Delivered_ce              uint32 `json:"delivered_ce"`
+	Bytes_sent                uint64 `json:"bytes_sent"`    /* RFC4898 tcpEStatsPerfHCDataOctetsOut */
+	Bytes_retrans             uint64 `json:"bytes_retrans"` /* RFC4898 tcpEStatsPerfOctetsRetrans */
+	Dsack_dups                uint32 `json:"dsack_dups"`    /* RFC4898 tcpEStatsStackDSACKDups */
+	Reord_seen                uint32 `json:"reord_seen"`    /* reordering events seen */
+	Rcv_ooopack               uint32 `json:"rcv_ooopack"`   /* Out-of-order packets received */
+	Snd_wnd                   uint32 `json:"snd_wnd"`       /* peer's advertised receive window after scaling (bytes) */
+	Rcv_wnd                   uint32 `json:"rcv_wnd"`       /* local advertised receive window after scaling (bytes) */
+	Rehash                    uint32 `json:"rehash"`        /* PLB or timeout triggered rehash attempts */
+}
+
+// Has reports whether the kernel sent the field ending at size, see the
+// SizeOfTCPInfo* constants.
+func (t *TCPInfo) Has(size int) bool {
+	return t.Length >= size
+}
+
+// ParseTCPInfo decodes the payload of INET_DIAG_INFO. Only the bytes the
+// kernel sent are read, fields it does not know about stay zero and fields
+// of newer kernels than this decoder are ignored.
+func ParseTCPInfo(data []byte) *TCPInfo {
+	var raw tcpInfo
+	length := copy((*[SizeOfTCPInfo]byte)(unsafe.Pointer(&raw))[:], data)
+	return &TCPInfo{
+		Length:                    length,
+		State:                     raw.State,
+		Ca_state:                  raw.Ca_state,
+		Retransmits:               raw.Retransmits,
+		Probes:                    raw.Probes,
+		Backoff:                   raw.Backoff,
+		Options:                   raw.Options,
+		Snd_wscale:                raw.Wscale & 0xf,
+		Rcv_wscale:                raw.Wscale >> 4,
+		Delivery_rate_app_limited: raw.Flags&1 != 0,
+		Fastopen_client_fail:      raw.Flags >> 1 & 0x3,
+		Rto:                       raw.Rto,
+		Ato:                       raw.Ato,
+		Snd_mss:                   raw.Snd_mss,
+		Rcv_mss:                   raw.Rcv_mss,
+		Unacked:                   raw.Unacked,
+		Sacked:                    raw.Sacked,
+		Lost:                      raw.Lost,
+		Retrans:                   raw.Retrans,
+		Fackets:                   raw.Fackets,
+		Last_data_sent:            raw.Last_data_sent,
+		Last_ack_sent:             raw.Last_ack_sent,
+		Last_data_recv:            raw.Last_data_recv,
+		Last_ack_recv:             raw.Last_ack_recv,
+		Pmtu:                      raw.Pmtu,
+		Rcv_ssthresh:              raw.Rcv_ssthresh,
+		Rtt:                       raw.Rtt,
+		Rttvar:                    raw.Rttvar,
+		Snd_ssthresh:              raw.Snd_ssthresh,
+		Snd_cwnd:                  raw.Snd_cwnd,
+		Advmss:                    raw.Advmss,
+		Reordering:                raw.Reordering,
+		Rcv_rtt:                   raw.Rcv_rtt,
+		Rcv_space:                 raw.Rcv_space,
+		Total_retrans:             raw.Total_retrans,
+		Pacing_rate:               raw.Pacing_rate,
+		Max_pacing_rate:           raw.Max_pacing_rate,
+		Bytes_acked:               raw.Bytes_acked,
+		Bytes_received:            raw.Bytes_received,
+		Segs_out:                  raw.Segs_out,
+		Segs_in:                   raw.Segs_in,
+		Notsent_bytes:             raw.Notsent_bytes,
+		Min_rtt:                   raw.Min_rtt,
+		Data_segs_in:              raw.Data_segs_in,
+		Data_segs_out:             raw.Data_segs_out,
+		Delivery_rate:             raw.Delivery_rate,
+		Busy_time:                 raw.Busy_time,
+		Rwnd_limited:              raw.Rwnd_limited,
+		Sndbuf_limited:            raw.Sndbuf_limited,
+		Delivered:                 raw.Delivered,
+		Delivered_ce:              raw.Delivered_ce,
+		Bytes_sent:                raw.Bytes_sent,
+		Bytes_retrans:             raw.Bytes_retrans,
+		Dsack_dups:                raw.Dsack_dups,
+		Reord_seen:                raw.Reord_seen,
+		Rcv_ooopack:               raw.Rcv_ooopack,
+		Snd_wnd:                   raw.Snd_wnd,
+		Rcv_wnd:                   raw.Rcv_wnd,
+		Rehash:                    raw.Rehash,
+	}
+}
@@ -0,0 +1,307 @@
+// +build linux
+
+package psss
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// a capture directory holds the sock_diag answers and a copy of the proc
+// files, laid out as below ProcRoot
+const (
+	CaptureDiagFile = "sock_diag"
+	CaptureProcDir  = "proc"
+)
+
+// Transport carries the sock_diag requests of a Client. Errors are returned
+// as the bare errno, the Client turns them into a *NetlinkError.
+type Transport interface {
+	// Send starts a request.
+	Send(request []byte) error
+	// Recv returns the next datagram of the answer, which is only valid until
+	// the next call.
+	Recv() ([]byte, error)
+	// Reset drops the rest of an answer that is not read to its end.
+	Reset() error
+	Close() error
+}
+
+type netlinkTransport struct {
+	netns  *NetNS
+	skfd   int
+	buffer []byte
+}
+
+// NewNetlinkTransport returns the Transport a Client uses by default, a
+// NETLINK_SOCK_DIAG socket in ns, opened when the first request is sent.
+func NewNetlinkTransport(ns *NetNS) Transport {
+	return &netlinkTransport{
+		netns:  ns,
+		skfd:   -1,
+		buffer: make([]byte, OSPageSize),
+	}
+}
+
+func (t *netlinkTransport) open() (err error) {
+	if t.skfd >= 0 {
+		return nil
+	}
+	open := func() error {
+		t.skfd, err = unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_SOCK_DIAG)
+		return err
+	}
+	if t.netns != nil {
+		// a netlink socket answers for the namespace it was created in
+		err = t.netns.Do(open)
+	} else {
+		err = open()
+	}
+	if err != nil {
+		t.skfd = -1
+		return err
+	}
+	// ask for the reason of failures, kernels without extended ACKs refuse
+	unix.SetsockoptInt(t.skfd, unix.SOL_NETLINK, unix.NETLINK_EXT_ACK, 1)
+	return nil
+}
+
+func (t *netlinkTransport) Send(request []byte) (err error) {
+	if err = t.open(); err != nil {
+		return err
+	}
+	if err = unix.Sendmsg(t.skfd, request, nil, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}, 0); err != nil {
+		t.Reset()
+	}
+	return err
+}
+
+func (t *netlinkTransport) Recv() ([]byte, error) {
+	return recvDatagram(t.skfd, &t.buffer)
+}
+
+// Reset closes the socket, the unread answer would otherwise be taken for the
+// answer of the next request.
+func (t *netlinkTransport) Reset() error {
+	return t.Close()
+}
+
+func (t *netlinkTransport) Close() (err error) {
+	if t.skfd >= 0 {
+		err = unix.Close(t.skfd)
+		t.skfd = -1
+	}
+	return err
+}
+
+// diagExchange is a request and its answer in a capture. Errno is the error
+// that ended the answer, SendErrno the one that refused the request.
+type diagExchange struct {
+	Request   []byte   `json:"request"`
+	Answer    [][]byte `json:"answer,omitempty"`
+	Errno     int      `json:"errno,omitempty"`
+	SendErrno int      `json:"send_errno,omitempty"`
+}
+
+func errnoOf(err error) int {
+	if errno, ok := err.(syscall.Errno); ok {
+		return int(errno)
+	}
+	return int(unix.EIO)
+}
+
+type captureTransport struct {
+	Transport
+	w   io.WriteCloser
+	enc *json.Encoder
+	ex  *diagExchange
+	err error
+}
+
+// NewCaptureTransport passes the requests on to t and writes every request
+// with its answer to w, one JSON object per line. Close closes t and w.
+func NewCaptureTransport(t Transport, w io.WriteCloser) Transport {
+	return &captureTransport{Transport: t, w: w, enc: json.NewEncoder(w)}
+}
+
+func (t *captureTransport) flush() {
+	if t.ex != nil && t.err == nil {
+		t.err = t.enc.Encode(t.ex)
+	}
+	t.ex = nil
+}
+
+func (t *captureTransport) Send(request []byte) error {
+	t.flush()
+	t.ex = &diagExchange{Request: append([]byte(nil), request...)}
+	err := t.Transport.Send(request)
+	if err != nil {
+		t.ex.SendErrno = errnoOf(err)
+	}
+	return err
+}
+
+func (t *captureTransport) Recv() ([]byte, error) {
+	data, err := t.Transport.Recv()
+	switch {
+	case t.ex == nil:
+	case err == nil:
+		t.ex.Answer = append(t.ex.Answer, append([]byte(nil), data...))
+	case err != unix.EINTR:
+		t.ex.Errno = errnoOf(err)
+	}
+	return data, err
+}
+
+func (t *captureTransport) Reset() error {
+	t.flush()
+	return t.Transport.Reset()
+}
+
+// Close returns the first error met while writing the capture.
+func (t *captureTransport) Close() error {
+	t.flush()
+	t.Transport.Close()
+	if err := t.w.Close(); t.err == nil {
+		t.err = err
+	}
+	return t.err
+}
+
+type replayTransport struct {
+	exchanges map[string][]*diagExchange
+	ex        *diagExchange
+	pos       int
+}
+
+// NewReplayTransport answers the requests recorded by NewCaptureTransport in
+// r. Repeated requests get the recorded answers in order, the last one once
+// they are used up. Requests that were not recorded are refused with ENOENT,
+// which is what sock_diag answers for a family or protocol it lacks.
+func NewReplayTransport(r io.Reader) (Transport, error) {
+	t := &replayTransport{exchanges: make(map[string][]*diagExchange)}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64<<20)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		ex := new(diagExchange)
+		if err := json.Unmarshal(scanner.Bytes(), ex); err != nil {
+			return nil, fmt.Errorf("parse capture error:[%v]", err)
+		}
+		key := string(ex.Request)
+		t.exchanges[key] = append(t.exchanges[key], ex)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *replayTransport) Send(request []byte) error {
+	t.ex, t.pos = nil, 0
+	exchanges := t.exchanges[string(request)]
+	if len(exchanges) == 0 {
+		return unix.ENOENT
+	}
+	t.ex = exchanges[0]
+	if len(exchanges) > 1 {
+		t.exchanges[string(request)] = exchanges[1:]
+	}
+	if t.ex.SendErrno != 0 {
+		return syscall.Errno(t.ex.SendErrno)
+	}
+	return nil
+}
+
+func (t *replayTransport) Recv() ([]byte, error) {
+	switch {
+	case t.ex == nil:
+		return nil, unix.EBADF
+	case t.pos < len(t.ex.Answer):
+		t.pos++
+		return t.ex.Answer[t.pos-1], nil
+	case t.ex.Errno != 0:
+		return nil, syscall.Errno(t.ex.Errno)
+	}
+	// the capture stopped before the answer was complete
+	return nil, unix.EIO
+}
+
+func (t *replayTransport) Reset() error {
+	t.ex = nil
+	return nil
+}
+
+func (t *replayTransport) Close() error {
+	return t.Reset()
+}
+
+// CaptureProc copies the proc files c reads sockets from to dir, laid out as
+// below ProcRoot. Files the kernel does not have are left out.
+func (c *Client) CaptureProc(dir string) (err error) {
+	for key, path := range procFilePath {
+		if err = c.copyProc(key, filepath.Join(dir, path)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) copyProc(key, dst string) (err error) {
+	src, err := c.openProc(key)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	if err = os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(file, src); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// CreateCapture creates the capture directory dir with the proc files of ns
+// and returns a Transport in ns that records into it until closed.
+func CreateCapture(dir string, ns *NetNS) (Transport, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	c := NewClient()
+	c.NetNS = ns
+	defer c.Close()
+	if err := c.CaptureProc(filepath.Join(dir, CaptureProcDir)); err != nil {
+		return nil, err
+	}
+	file, err := os.Create(filepath.Join(dir, CaptureDiagFile))
+	if err != nil {
+		return nil, err
+	}
+	return NewCaptureTransport(NewNetlinkTransport(ns), file), nil
+}
+
+// OpenReplay loads the sock_diag answers of the capture directory dir. Clients
+// replaying it also need their ProcRoot set to dir/proc.
+func OpenReplay(dir string) (Transport, error) {
+	file, err := os.Open(filepath.Join(dir, CaptureDiagFile))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return NewReplayTransport(file)
+}
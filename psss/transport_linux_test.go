@@ -0,0 +1,209 @@
+// +build linux
+
+package psss
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// testdata/capture was recorded with CreateCapture in a namespace holding a
+// TCP listener on 127.0.0.1:8080 with a connection from port 40000, and a
+// unix stream listener on /tmp/psss-test.sock with one connection and a
+// datagram socket bound to @psss-test. The dumps were those of a NewClient
+// with Info set.
+const captureDir = "testdata/capture"
+
+func replayClient(t *testing.T) *Client {
+	transport, err := OpenReplay(captureDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient()
+	c.Info = true
+	c.ProcRoot = filepath.Join(captureDir, CaptureProcDir)
+	c.Transport = transport
+	return c
+}
+
+// procReplayClient replays a kernel without sock_diag, so that the sockets
+// come from the recorded proc files.
+func procReplayClient(t *testing.T) *Client {
+	transport, err := NewReplayTransport(strings.NewReader(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient()
+	c.ProcRoot = filepath.Join(captureDir, CaptureProcDir)
+	c.Transport = transport
+	return c
+}
+
+// collect reads q into a map keyed by inode.
+func collect(t *testing.T, c *Client, q SocketQuery) map[uint32]SocketInfo {
+	sis := make(map[uint32]SocketInfo)
+	err := c.ForEachSocket(q, func(si *SocketInfo) bool {
+		sis[si.Inode] = *si
+		return true
+	})
+	if err != nil {
+		t.Fatalf("ForEachSocket: %v", err)
+	}
+	return sis
+}
+
+func TestReplayInetDiag(t *testing.T) {
+	sis := collect(t, replayClient(t), SocketQuery{Protocal: ProtocalTCP, Af: unix.AF_INET})
+	if len(sis) != 3 {
+		t.Fatalf("got %d sockets, want 3", len(sis))
+	}
+	for _, tt := range []struct {
+		inode            uint32
+		local, remote    string
+		status           uint8
+		rxQueue, txQueue uint32
+	}{
+		{75614, "127.0.0.1:8080", "0.0.0.0:0", SsLISTEN, 0, 128},
+		{75615, "127.0.0.1:40000", "127.0.0.1:8080", SsESTAB, 0, 5},
+		{75616, "127.0.0.1:8080", "127.0.0.1:40000", SsESTAB, 99005, 0},
+	} {
+		si, ok := sis[tt.inode]
+		if !ok {
+			t.Errorf("inode %d missing", tt.inode)
+			continue
+		}
+		if si.Source != SourceNetlink || si.Protocal != ProtocalTCP {
+			t.Errorf("inode %d: source %v protocal %d", tt.inode, si.Source, si.Protocal)
+		}
+		if si.LocalAddr.String() != tt.local || si.RemoteAddr.String() != tt.remote {
+			t.Errorf("inode %d: %s -> %s, want %s -> %s", tt.inode, si.LocalAddr, si.RemoteAddr, tt.local, tt.remote)
+		}
+		if si.Status != tt.status || si.RxQueue != tt.rxQueue || si.TxQueue != tt.txQueue {
+			t.Errorf("inode %d: state %d queues %d/%d, want %d %d/%d", tt.inode,
+				si.Status, si.RxQueue, si.TxQueue, tt.status, tt.rxQueue, tt.txQueue)
+		}
+		if si.TCPInfo == nil {
+			t.Errorf("inode %d: no INET_DIAG_INFO", tt.inode)
+		}
+	}
+
+	info := sis[75615].TCPInfo
+	if info == nil {
+		return
+	}
+	if info.State != SsESTAB || info.Snd_mss != 47616 || info.Rtt != 46 || info.Rttvar != 20 {
+		t.Errorf("state %d snd_mss %d rtt %d/%d, want %d 47616 46/20", info.State, info.Snd_mss, info.Rtt, info.Rttvar, SsESTAB)
+	}
+	if info.Bytes_acked != 100001 || info.Bytes_sent != 100005 || info.Segs_out != 6 {
+		t.Errorf("bytes_acked %d bytes_sent %d segs_out %d, want 100001 100005 6", info.Bytes_acked, info.Bytes_sent, info.Segs_out)
+	}
+	if info.Snd_wscale != 10 || info.Rcv_wscale != 10 || info.Snd_cwnd != 13 {
+		t.Errorf("wscale %d,%d cwnd %d, want 10,10 13", info.Snd_wscale, info.Rcv_wscale, info.Snd_cwnd)
+	}
+}
+
+func TestReplayUnixDiag(t *testing.T) {
+	sis := collect(t, replayClient(t), SocketQuery{Protocal: ProtocalUnix, Af: unix.AF_UNIX})
+	if len(sis) != 4 {
+		t.Fatalf("got %d sockets, want 4", len(sis))
+	}
+	for _, tt := range []struct {
+		inode   uint32
+		name    string
+		typ     uint8
+		status  uint8
+		peer    uint32
+		rxQueue uint32
+	}{
+		{75617, "/tmp/psss-test.sock", unix.SOCK_STREAM, SsLISTEN, 0, 0},
+		{75620, "/tmp/psss-test.sock", unix.SOCK_STREAM, SsESTAB, 75619, 5},
+		{75619, "", unix.SOCK_STREAM, SsESTAB, 75620, 0},
+		{75618, "@psss-test", unix.SOCK_DGRAM, SsUNCONN, 0, 0},
+	} {
+		si, ok := sis[tt.inode]
+		if !ok {
+			t.Errorf("inode %d missing", tt.inode)
+			continue
+		}
+		if si.Source != SourceNetlink {
+			t.Errorf("inode %d: source %v, want SourceNetlink", tt.inode, si.Source)
+		}
+		if si.LocalAddr.Name != tt.name || si.UnixName.String() != tt.name {
+			t.Errorf("inode %d: name %q/%q, want %q", tt.inode, si.LocalAddr.Name, si.UnixName, tt.name)
+		}
+		if si.Type != tt.typ || si.Status != tt.status || si.RemoteAddr.ID != tt.peer || si.RxQueue != tt.rxQueue {
+			t.Errorf("inode %d: type %d state %d peer %d rx %d, want %d %d %d %d", tt.inode,
+				si.Type, si.Status, si.RemoteAddr.ID, si.RxQueue, tt.typ, tt.status, tt.peer, tt.rxQueue)
+		}
+	}
+	if sis[75617].UnixVFS == nil {
+		t.Errorf("no UNIX_DIAG_VFS for the bound listener")
+	}
+}
+
+func TestReplayProcTCP(t *testing.T) {
+	sis := collect(t, procReplayClient(t), SocketQuery{Protocal: ProtocalTCP, Af: unix.AF_INET})
+	if len(sis) != 3 {
+		t.Fatalf("got %d sockets, want 3", len(sis))
+	}
+	for _, tt := range []struct {
+		inode            uint32
+		local, remote    string
+		status           uint8
+		rxQueue, txQueue uint32
+	}{
+		{75614, "127.0.0.1:8080", "0.0.0.0:0", SsLISTEN, 0, 0},
+		{75615, "127.0.0.1:40000", "127.0.0.1:8080", SsESTAB, 0, 5},
+		{75616, "127.0.0.1:8080", "127.0.0.1:40000", SsESTAB, 99005, 0},
+	} {
+		si, ok := sis[tt.inode]
+		if !ok {
+			t.Errorf("inode %d missing", tt.inode)
+			continue
+		}
+		if si.Source != SourceProc {
+			t.Errorf("inode %d: source %v, want SourceProc", tt.inode, si.Source)
+		}
+		if si.LocalAddr.String() != tt.local || si.RemoteAddr.String() != tt.remote {
+			t.Errorf("inode %d: %s -> %s, want %s -> %s", tt.inode, si.LocalAddr, si.RemoteAddr, tt.local, tt.remote)
+		}
+		if si.Status != tt.status || si.RxQueue != tt.rxQueue || si.TxQueue != tt.txQueue {
+			t.Errorf("inode %d: state %d queues %d/%d, want %d %d/%d", tt.inode,
+				si.Status, si.RxQueue, si.TxQueue, tt.status, tt.rxQueue, tt.txQueue)
+		}
+	}
+}
+
+func TestReplayProcUnix(t *testing.T) {
+	sis := collect(t, procReplayClient(t), SocketQuery{Protocal: ProtocalUnix, Af: unix.AF_UNIX})
+	if len(sis) != 4 {
+		t.Fatalf("got %d sockets, want 4", len(sis))
+	}
+	for _, tt := range []struct {
+		inode  uint32
+		name   string
+		typ    uint8
+		status uint8
+	}{
+		{75617, "/tmp/psss-test.sock", unix.SOCK_STREAM, SsLISTEN},
+		{75620, "/tmp/psss-test.sock", unix.SOCK_STREAM, SsESTAB},
+		{75619, "", unix.SOCK_STREAM, SsESTAB},
+		{75618, "@psss-test", unix.SOCK_DGRAM, SsUNCONN},
+	} {
+		si, ok := sis[tt.inode]
+		if !ok {
+			t.Errorf("inode %d missing", tt.inode)
+			continue
+		}
+		if si.Source != SourceProc {
+			t.Errorf("inode %d: source %v, want SourceProc", tt.inode, si.Source)
+		}
+		if si.LocalAddr.Name != tt.name || si.Type != tt.typ || si.Status != tt.status {
+			t.Errorf("inode %d: name %q type %d state %d, want %q %d %d", tt.inode,
+				si.LocalAddr.Name, si.Type, si.Status, tt.name, tt.typ, tt.status)
+		}
+	}
+}
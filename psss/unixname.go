@@ -0,0 +1,79 @@
+package psss
+
+import (
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	UnixNameUnnamed = iota
+	UnixNamePath
+	UnixNameAbstract
+)
+
+// UnixName is the address a unix socket is bound to. Name holds the raw
+// bytes, without the leading NUL of an abstract name.
+type UnixName struct {
+	Kind int
+	Name string
+}
+
+// ParseUnixName decodes the sun_path bytes of UNIX_DIAG_NAME. A path ends at
+// its first NUL, an abstract name starts with one and may contain more.
+func ParseUnixName(raw []byte) UnixName {
+	switch {
+	case len(raw) == 0:
+		return UnixName{Kind: UnixNameUnnamed}
+	case raw[0] == 0:
+		return UnixName{Kind: UnixNameAbstract, Name: string(raw[1:])}
+	}
+	if i := strings.IndexByte(string(raw), 0); i >= 0 {
+		raw = raw[:i]
+	}
+	return UnixName{Kind: UnixNamePath, Name: string(raw)}
+}
+
+// String is safe to print: abstract names start with '@' and the name is
+// escaped by EscapeUnixName. Unnamed sockets return an empty string.
+func (n UnixName) String() string {
+	switch n.Kind {
+	case UnixNamePath:
+		return EscapeUnixName(n.Name)
+	case UnixNameAbstract:
+		return "@" + EscapeUnixName(n.Name)
+	}
+	return ""
+}
+
+// EscapeUnixName writes control characters, invalid UTF-8 and the backslash
+// as \xHH and \\, so that a name can not move the cursor of a terminal.
+func EscapeUnixName(name string) string {
+	var b strings.Builder
+	for i := 0; i < len(name); {
+		r, size := utf8.DecodeRuneInString(name[i:])
+		switch {
+		case r == utf8.RuneError && size == 1, r < 0x20, r == 0x7f, r >= 0x80 && r < 0xa0:
+			for j := 0; j < size; j++ {
+				b.WriteString(`\x`)
+				b.WriteByte("0123456789abcdef"[name[i+j]>>4])
+				b.WriteByte("0123456789abcdef"[name[i+j]&0xf])
+			}
+		case r == '\\':
+			b.WriteString(`\\`)
+		default:
+			b.WriteString(name[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// globMatch is fnmatch(3) without FNM_PATHNAME, so '*' also matches '/'.
+// path.Match only stops at '/', which is hidden from it by swapping it for a
+// byte that can not appear in an escaped name.
+func globMatch(pattern, name string) bool {
+	swap := func(s string) string { return strings.Replace(s, "/", "\x01", -1) }
+	matched, err := path.Match(swap(pattern), swap(name))
+	return err == nil && matched
+}
@@ -0,0 +1,89 @@
+package psss
+
+import (
+	"testing"
+)
+
+func TestParseUnixName(t *testing.T) {
+	for _, tt := range []struct {
+		raw    string
+		kind   int
+		name   string
+		escape string
+	}{
+		{"", UnixNameUnnamed, "", ""},
+		{"/run/a.sock", UnixNamePath, "/run/a.sock", "/run/a.sock"},
+		// sun_path may be padded with NULs, or hold garbage after the first
+		{"/run/a.sock\x00\x00", UnixNamePath, "/run/a.sock", "/run/a.sock"},
+		{"/run/a\x00junk", UnixNamePath, "/run/a", "/run/a"},
+		{"\x00abstract", UnixNameAbstract, "abstract", "@abstract"},
+		// abstract names are the whole buffer, NULs included
+		{"\x00a\x00b\x00", UnixNameAbstract, "a\x00b\x00", `@a\x00b\x00`},
+		{"\x00", UnixNameAbstract, "", "@"},
+		{"/tmp/\xff\xfe", UnixNamePath, "/tmp/\xff\xfe", `/tmp/\xff\xfe`},
+	} {
+		n := ParseUnixName([]byte(tt.raw))
+		if n.Kind != tt.kind || n.Name != tt.name {
+			t.Errorf("%q: got kind %d name %q, want %d %q", tt.raw, n.Kind, n.Name, tt.kind, tt.name)
+		}
+		if got := n.String(); got != tt.escape {
+			t.Errorf("%q: String() = %q, want %q", tt.raw, got, tt.escape)
+		}
+	}
+}
+
+func TestEscapeUnixName(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		want string
+	}{
+		{"/run/dbus/system_bus_socket", "/run/dbus/system_bus_socket"},
+		{"a\nb\tc\x1b[2J", `a\x0ab\x09c\x1b[2J`},
+		{"del\x7f", `del\x7f`},
+		{`back\slash`, `back\\slash`},
+		// C1 controls are valid UTF-8, but terminals act on them
+		{"csi\u009b2J", `csi\xc2\x9b2J`},
+		{"\u0080\u009f", `\xc2\x80\xc2\x9f`},
+		{"nbsp\u00a0", "nbsp\u00a0"}, // U+00A0 is past the C1 range
+		{"/tmp/日本語.sock", "/tmp/日本語.sock"},
+		// invalid UTF-8 is escaped a byte at a time
+		{"\xff", `\xff`},
+		{"\xe6\x97", `\xe6\x97`},
+		{"\xc0\xaf", `\xc0\xaf`},         // overlong '/'
+		{"\xed\xa0\x80", `\xed\xa0\x80`}, // surrogate
+	} {
+		if got := EscapeUnixName(tt.name); got != tt.want {
+			t.Errorf("EscapeUnixName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGlobMatch(t *testing.T) {
+	for _, tt := range []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"/run/*.sock", "/run/a.sock", true},
+		// as fnmatch(3) without FNM_PATHNAME, '*' and '?' cross '/'
+		{"/run/*", "/run/user/1000/bus", true},
+		{"/run/*.sock", "/run/user/a.sock", true},
+		{"/run?user", "/run/user", true},
+		{"*", "/run/a.sock", true},
+		{"/run/*", "/var/run/a.sock", false},
+		{"/run/*/bus", "/run/bus", false},
+		// brackets still see the '/', and a quoted '/' is a literal one
+		{"/run[/]a", "/run/a", true},
+		{"/run[^/]a", "/run/a", false},
+		{"/run[^/]a", "/runxa", true},
+		{`/run\/a`, "/run/a", true},
+		{"@*", "@abstract", true},
+		{"@*", "/run/a", false},
+		// names are matched in their escaped form
+		{`/tmp/\\x1b*`, `/tmp/\x1b[2J`, true},
+		{"/run/[", "/run/[", false},
+	} {
+		if got := globMatch(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("globMatch(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+// +build linux
+
+package psss
+
+import (
+	"fmt"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// sock_diag multicast groups, a socket is announced when it is destroyed
+const (
+	SKNLGRP_NONE = iota
+	SKNLGRP_INET_TCP_DESTROY
+	SKNLGRP_INET_UDP_DESTROY
+	SKNLGRP_INET6_TCP_DESTROY
+	SKNLGRP_INET6_UDP_DESTROY
+)
+
+const (
+	SocketEventNew = iota
+	SocketEventClosed
+	SocketEventState
+	SocketEventDestroyed
+	SocketEventLost
+)
+
+var (
+	SocketEventName = []string{
+		"NEW",
+		"CLOSED",
+		"STATE",
+		"DESTROYED",
+		"LOST",
+	}
+
+	// watchPollTimeout bounds how long a blocked watch takes to notice stop
+	watchPollTimeout = 500 * time.Millisecond
+)
+
+// SocketEvent is one change of a socket. OldStatus is only set for
+// SocketEventState, Info holds the last known state of the socket. A
+// SocketEventLost has neither, it only tells that events were dropped.
+type SocketEvent struct {
+	Type      int
+	Time      time.Time
+	Family    int
+	OldStatus uint8
+	Info      SocketInfo
+}
+
+// DestroyGroups returns the SKNLGRP_* groups for the protocals and address
+// families in the given masks, only TCP and UDP are announced by the kernel.
+func DestroyGroups(protocals, afs uint64) (groups []int) {
+	if afs&(1<<unix.AF_INET) != 0 {
+		if protocals&ProtocalTCP != 0 {
+			groups = append(groups, SKNLGRP_INET_TCP_DESTROY)
+		}
+		if protocals&ProtocalUDP != 0 {
+			groups = append(groups, SKNLGRP_INET_UDP_DESTROY)
+		}
+	}
+	if afs&(1<<unix.AF_INET6) != 0 {
+		if protocals&ProtocalTCP != 0 {
+			groups = append(groups, SKNLGRP_INET6_TCP_DESTROY)
+		}
+		if protocals&ProtocalUDP != 0 {
+			groups = append(groups, SKNLGRP_INET6_UDP_DESTROY)
+		}
+	}
+	return groups
+}
+
+// inetDiagProtocal reads INET_DIAG_PROTOCOL, which destroy notifications
+// carry since there is no request telling the protocol.
+func inetDiagProtocal(data []byte) int {
+	var nlAttr unix.NlAttr
+	for cursor := SizeOfInetDiagMsg; cursor+unix.SizeofNlAttr <= len(data); cursor += int(nlAttr.Len+unix.NLA_ALIGNTO-1) & ^(unix.NLA_ALIGNTO - 1) {
+		nlAttr = *(*unix.NlAttr)(unsafe.Pointer(&data[cursor]))
+		if nlAttr.Len < unix.SizeofNlAttr || cursor+int(nlAttr.Len) > len(data) {
+			break
+		}
+		if nlAttr.Type != INET_DIAG_PROTOCOL || nlAttr.Len < unix.SizeofNlAttr+1 {
+			continue
+		}
+		switch data[cursor+unix.SizeofNlAttr] {
+		case unix.IPPROTO_TCP:
+			return ProtocalTCP
+		case unix.IPPROTO_UDP:
+			return ProtocalUDP
+		case unix.IPPROTO_UDPLITE:
+			return ProtocalUDPLite
+		}
+	}
+	return 0
+}
+
+// WatchDestroy subscribes to the SKNLGRP_* groups and hands every destroyed
+// socket, with its final TCPInfo, to fn until fn returns false or stop is
+// closed. Subscribing needs CAP_NET_ADMIN. When the receive buffer overflowed
+// and the kernel dropped notifications, fn gets a SocketEventLost and the
+// watch goes on.
+func (c *Client) WatchDestroy(groups []int, stop <-chan struct{}, fn func(ev *SocketEvent) bool) (err error) {
+	var (
+		skfd   int
+		mask   uint32
+		buffer = make([]byte, OSPageSize)
+	)
+	for _, group := range groups {
+		if group <= SKNLGRP_NONE || group > SKNLGRP_INET6_UDP_DESTROY {
+			return fmt.Errorf("invalid group:[%d]", group)
+		}
+		mask |= 1 << uint(group-1)
+	}
+	open := func() (err error) {
+		skfd, err = unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_SOCK_DIAG)
+		return err
+	}
+	if c.NetNS != nil {
+		// notifications are only sent to listeners of the same namespace
+		err = c.NetNS.Do(open)
+	} else {
+		err = open()
+	}
+	if err != nil {
+		return wrapErrno(err)
+	}
+	defer unix.Close(skfd)
+	if err = unix.Bind(skfd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: mask}); err != nil {
+		// the groups are only open to CAP_NET_ADMIN, see ErrPermission
+		return wrapErrno(err)
+	}
+	unix.SetsockoptInt(skfd, unix.SOL_SOCKET, unix.SO_RCVBUF, 1<<20)
+	tv := unix.NsecToTimeval(int64(watchPollTimeout))
+	if err = unix.SetsockoptTimeval(skfd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv); err != nil {
+		return err
+	}
+
+	ev := new(SocketEvent)
+	for {
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+		raw, err := recvDiagMsgMulti(skfd, &buffer)
+		switch err {
+		case nil:
+		case unix.EAGAIN, unix.EINTR:
+			continue
+		case unix.ENOBUFS:
+			*ev = SocketEvent{Type: SocketEventLost, Time: time.Now()}
+			if !fn(ev) {
+				return nil
+			}
+			continue
+		default:
+			return wrapErrno(err)
+		}
+		for i := range raw {
+			if raw[i].Header.Type != SOCK_DIAG_BY_FAMILY {
+				continue
+			}
+			ev.Info.Reset()
+			ev.Type = SocketEventDestroyed
+			ev.Time = time.Now()
+			ev.Info.Protocal = inetDiagProtocal(raw[i].Data)
+			ev.Info.Source = SourceNetlink
+			if parseInetDiagMsg(raw[i].Data, &ev.Info) != nil {
+				continue
+			}
+			ev.Family = int(raw[i].Data[0])
+			if c.NetNS != nil {
+				ev.Info.NetNS = c.NetNS.Name
+			}
+			if c.Filter != nil && !c.Filter.Match(&ev.Info) {
+				continue
+			}
+			if !fn(ev) {
+				return nil
+			}
+		}
+	}
+}
+
+type socketKey struct {
+	protocal int
+	sk       uint64
+	local    SockAddr
+	remote   SockAddr
+}
+
+type socketSnapshot map[socketKey]*SocketEvent
+
+func (c *Client) snapshot(queries []SocketQuery) (snap socketSnapshot, err error) {
+	snap = make(socketSnapshot)
+	for _, q := range queries {
+		err = c.ForEachSocket(q, func(si *SocketInfo) bool {
+			key := socketKey{protocal: si.Protocal, sk: si.SK, local: si.LocalAddr, remote: si.RemoteAddr}
+			snap[key] = &SocketEvent{Family: q.Af, Info: *si}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return snap, nil
+}
+
+// diffSnapshots reports the sockets only in cur as new, the ones only in prev
+// as closed and the ones whose state differs as state changes.
+func diffSnapshots(prev, cur socketSnapshot, now time.Time, fn func(ev *SocketEvent) bool) bool {
+	for key, ev := range cur {
+		old, ok := prev[key]
+		switch {
+		case !ok:
+			ev.Type = SocketEventNew
+		case old.Info.Status != ev.Info.Status:
+			ev.Type = SocketEventState
+			ev.OldStatus = old.Info.Status
+		default:
+			continue
+		}
+		ev.Time = now
+		if !fn(ev) {
+			return false
+		}
+	}
+	for key, ev := range prev {
+		if _, ok := cur[key]; ok {
+			continue
+		}
+		ev.Type = SocketEventClosed
+		ev.Time = now
+		if !fn(ev) {
+			return false
+		}
+	}
+	return true
+}
+
+// WatchPoll reads the sockets of queries every interval and reports what
+// changed since the previous read, see diffSnapshots. The first read only
+// sets the baseline. Sockets living shorter than interval are not seen, use
+// WatchDestroy for those where possible.
+func (c *Client) WatchPoll(queries []SocketQuery, interval time.Duration, stop <-chan struct{}, fn func(ev *SocketEvent) bool) error {
+	prev, err := c.snapshot(queries)
+	if err != nil {
+		return err
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case now := <-ticker.C:
+			cur, err := c.snapshot(queries)
+			if err != nil {
+				return err
+			}
+			if !diffSnapshots(prev, cur, now, fn) {
+				return nil
+			}
+			prev = cur
+		}
+	}
+}
@@ -3,9 +3,9 @@ package topo
 import (
 	"bytes"
 	"net"
+	"net/netip"
 	"os"
 	"runtime"
-	"strings"
 
 	"github.com/buck119br/psss/psss"
 	"github.com/glycerine/zebrapack/msgp"
@@ -14,7 +14,7 @@ import (
 var (
 	numCPU     uint64
 	pageSize   uint64
-	localAddrs []string
+	localAddrs []netip.Addr
 
 	SysStatNew     *psss.SystemStat
 	SysStatOld     *psss.SystemStat
@@ -24,13 +24,13 @@ var (
 	MsgpWriter *msgp.Writer
 
 	procsInfoReserve map[string]map[int]*ProcInfoReserve
-	localPortToName  map[string]string
+	localPortToName  map[uint16]string
 
 	originProcInfo  *psss.ProcInfo
 	procInfoReserve *ProcInfoReserve
 	procStat        ProcStat
 
-	addr      Addr
+	addr      netip.AddrPort
 	addrState AddrState
 	ok        bool
 )
@@ -40,11 +40,12 @@ func init() {
 	if err != nil {
 		panic(err)
 	}
-	localAddrs = make([]string, 0)
+	localAddrs = make([]netip.Addr, 0)
 	for _, v := range netAddrs {
-		localAddrs = append(localAddrs, strings.Split(v.String(), "/")[0])
+		if prefix, err := netip.ParsePrefix(v.String()); err == nil {
+			localAddrs = append(localAddrs, prefix.Addr())
+		}
 	}
-	localAddrs = append(localAddrs, "127.0.1.1")
 
 	numCPU = uint64(runtime.NumCPU())
 	pageSize = uint64(os.Getpagesize())
@@ -57,7 +58,7 @@ func init() {
 	MsgpWriter = msgp.NewWriter(MsgpBuffer)
 
 	procsInfoReserve = make(map[string]map[int]*ProcInfoReserve)
-	localPortToName = make(map[string]string)
+	localPortToName = make(map[uint16]string)
 
 	procInfoReserve = new(ProcInfoReserve)
 
@@ -1,5 +1,11 @@
 package topo
 
+import (
+	"net/netip"
+
+	"github.com/buck119br/psss/psss"
+)
+
 const zebraSchemaId64 int64 = 0x4df6151fb497 // 85719311692951
 
 type Addr struct {
@@ -12,7 +18,7 @@ type AddrState struct {
 	fresh bool
 }
 
-type AddrSet map[Addr]AddrState
+type AddrSet map[netip.AddrPort]AddrState
 
 type ProcStat struct {
 	StartTime   int64   `zid:"0"`
@@ -39,6 +45,7 @@ type Topology struct {
 	Services        map[string]*ServiceInfo `zid:"0"`
 	Time            int64                   `zid:"1"`
 	tempServiceInfo *ServiceInfo
-	tempAddr        Addr
+	tempAddr        netip.AddrPort
 	tempAddrState   AddrState
+	client          *psss.Client
 }
@@ -1,7 +1,7 @@
 package topo
 
 import (
-	"strings"
+	"net/netip"
 )
 
 type ProcInfoReserve struct {
@@ -26,9 +26,13 @@ func clearReserve() {
 	}
 }
 
-func isHostLocal(host string) bool {
+func isHostLocal(host netip.Addr) bool {
+	host = host.Unmap()
+	if host.IsLoopback() {
+		return true
+	}
 	for _, v := range localAddrs {
-		if strings.Contains(host, v) {
+		if host == v {
 			return true
 		}
 	}
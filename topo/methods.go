@@ -1,6 +1,7 @@
 package topo
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"time"
@@ -18,6 +19,8 @@ func NewServiceInfo() *ServiceInfo {
 func NewTopology() *Topology {
 	t := new(Topology)
 	t.Services = make(map[string]*ServiceInfo)
+	t.client = psss.NewClient()
+	t.client.Process = true
 	return t
 }
 
@@ -170,11 +173,11 @@ func (t *Topology) cleanAll() {
 	}
 }
 
-func (t *Topology) doPortListen(port string) bool {
+func (t *Topology) doPortListen(port uint16) bool {
 	for _, t.tempServiceInfo = range t.Services {
 		if t.tempServiceInfo.DoListen {
 			for t.tempAddr = range t.tempServiceInfo.addrs {
-				if port == t.tempAddr.Port {
+				if port == t.tempAddr.Port() {
 					return true
 				}
 			}
@@ -187,41 +190,42 @@ func (t *Topology) doUserListen(user string) bool {
 	return t.Services[user].DoListen
 }
 
-func (t *Topology) getSockInfo(af uint8, ssFilter uint32) (err error) {
-	skfd, err := psss.SendInetDiagMsg(af, unix.IPPROTO_TCP, 0, ssFilter)
-	if err != nil {
+func (t *Topology) getSockInfo(af int, ssFilter uint32) (err error) {
+	t.client.States = ssFilter
+	sis, err := t.client.InetRead(psss.ProtocalTCP, af)
+	if err != nil && !errors.Is(err, psss.ErrTruncatedMessage) {
 		return err
 	}
-	defer unix.Close(skfd)
 
-	var serviceInfo *ServiceInfo
-	go psss.RecvInetDiagMsgAll(skfd)
-	for si := range psss.SocketInfoChan {
-		if si.IsEnd {
-			return nil
-		}
+	var (
+		serviceInfo *ServiceInfo
+		userName    string
+	)
+	for _, si := range sis {
 		// handle socket info
-		localPortToName[si.LocalAddr.Port] = si.UserName
-		if serviceInfo, ok = t.Services[si.UserName]; !ok {
+		userName = ""
+		if len(si.Owners) > 0 {
+			userName = si.Owners[0].Name
+		}
+		localPortToName[si.LocalAddr.Port()] = userName
+		if serviceInfo, ok = t.Services[userName]; !ok {
 			continue
 		}
 		if si.Status == psss.SsLISTEN {
 			serviceInfo.DoListen = true
 			if serviceInfo.addrs == nil {
-				serviceInfo.addrs = make(map[Addr]AddrState)
+				serviceInfo.addrs = make(AddrSet)
 			}
-			addr.Host = si.LocalAddr.Host
-			addr.Port = si.LocalAddr.Port
+			addr = si.LocalAddr.AddrPort
 			addrState.Count = 1
 			addrState.fresh = true
 			serviceInfo.addrs[addr] = addrState
 		} else {
-			addr.Host = si.RemoteAddr.Host
-			addr.Port = si.RemoteAddr.Port
-			if t.doUserListen(si.UserName) {
-				if t.doPortListen(si.LocalAddr.Port) {
+			addr = si.RemoteAddr.AddrPort
+			if t.doUserListen(userName) {
+				if t.doPortListen(si.LocalAddr.Port()) {
 					if serviceInfo.downstream == nil {
-						serviceInfo.downstream = make(map[Addr]AddrState)
+						serviceInfo.downstream = make(AddrSet)
 					}
 					if addrState, ok = serviceInfo.downstream[addr]; !ok {
 						addrState.Count = 1
@@ -232,7 +236,7 @@ func (t *Topology) getSockInfo(af uint8, ssFilter uint32) (err error) {
 					serviceInfo.downstream[addr] = addrState
 				} else {
 					if serviceInfo.upstream == nil {
-						serviceInfo.upstream = make(map[Addr]AddrState)
+						serviceInfo.upstream = make(AddrSet)
 					}
 					if addrState, ok = serviceInfo.upstream[addr]; !ok {
 						addrState.Count = 1
@@ -244,13 +248,13 @@ func (t *Topology) getSockInfo(af uint8, ssFilter uint32) (err error) {
 				}
 				continue
 			}
-			if isHostLocal(si.RemoteAddr.Host) {
-				if t.doPortListen(si.RemoteAddr.Port) {
+			if isHostLocal(si.RemoteAddr.Addr()) {
+				if t.doPortListen(si.RemoteAddr.Port()) {
 					continue
 				}
 			}
 			if serviceInfo.addrs == nil {
-				serviceInfo.addrs = make(map[Addr]AddrState)
+				serviceInfo.addrs = make(AddrSet)
 			}
 			if addrState, ok = serviceInfo.addrs[addr]; !ok {
 				addrState.Count = 1
@@ -285,7 +289,7 @@ func (t *Topology) findUser() {
 			serviceInfo.UpStream = make(map[string]AddrState)
 		}
 		for addr, addrState = range serviceInfo.upstream {
-			if name, ok = localPortToName[addr.Port]; !ok {
+			if name, ok = localPortToName[addr.Port()]; !ok {
 				name = addr.String()
 			}
 			if t.tempAddrState, ok = serviceInfo.UpStream[name]; !ok {
@@ -308,7 +312,7 @@ func (t *Topology) findUser() {
 			serviceInfo.DownStream = make(map[string]AddrState)
 		}
 		for addr, addrState = range serviceInfo.downstream {
-			if name, ok = localPortToName[addr.Port]; !ok {
+			if name, ok = localPortToName[addr.Port()]; !ok {
 				name = addr.String()
 			}
 			if t.tempAddrState, ok = serviceInfo.DownStream[name]; !ok {
@@ -339,7 +343,6 @@ func (t *Topology) GetSockInfo() (err error) {
 	if err = t.getSockInfo(unix.AF_INET6, 1<<psss.SsESTAB); err != nil {
 		return err
 	}
-	psss.CleanGlobalProcFds()
 	t.findUser()
 	t.cleanAll()
 	t.Time = time.Now().Unix()